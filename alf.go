@@ -8,6 +8,13 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
 )
 
 // Root is your main, top-level command.
@@ -17,18 +24,118 @@ type Root struct {
 	// have been parsed but before a subcommand is chosen. Run will return early
 	// if this function returns an error.
 	PrePerform func(ctx context.Context) error
+	// UsageTemplate optionally changes the layout of generated help messages.
+	// A help message is generated for any flag set in the tree that doesn't
+	// have its own Usage func. The template is executed with a UsageData. If
+	// it's empty, then DefaultUsageTemplate is used.
+	UsageTemplate *template.Template
+	// EnvPrefix optionally lets flags be set from environment variables. When
+	// it's not empty, any flag in the tree that's not specified on the command
+	// line is set from an environment variable, if there is one. The variable
+	// name is the prefix, the subcommand names and the flag name, upper-cased
+	// and joined by underscores. For example, with the prefix "MYTOOL", flag
+	// "alpha" of subcommand "bar" is set from MYTOOL_BAR_ALPHA. The variables
+	// are listed in generated help messages. A flag defined by Bind with an
+	// env tag uses the name from the tag instead.
+	EnvPrefix string
+	// LoadConfig is an optional function to get flag values from somewhere
+	// else, such as a configuration file. It's invoked during Run, after the
+	// top-level flags have been parsed, so one of them could be the path to
+	// the file. A persistent flag specified after a subcommand is parsed
+	// later, so it's not a good fit for the path. See ReadConfigFile. Every
+	// section and key of the Config must match a command path and a flag
+	// defined at that level, or Run returns an error. Checking a non-empty
+	// Config calls the Setup func of every Command in the tree, see
+	// Command.Setup.
+	//
+	// The value of a flag comes from, in order of precedence: the command
+	// line, an environment variable (see EnvPrefix), the Config, and finally
+	// the flag's default value.
+	LoadConfig func(ctx context.Context) (Config, error)
+	// RecoverPanics makes Run recover from a panic in PrePerform, LoadConfig
+	// or any Setup or Run func in the tree. Instead of crashing, Run outputs a
+	// short message to stderr and returns a *PanicError. The stack trace is
+	// included in the message when Debug is true or the environment variable
+	// named by DebugEnv is set.
+	RecoverPanics bool
+	// Debug shows more details about recovered panics, see RecoverPanics.
+	// Consider binding it to a flag.
+	Debug bool
+	// Signals optionally makes Run cancel the context passed to PrePerform and
+	// Perform when the program receives one of these signals, usually
+	// os.Interrupt and syscall.SIGTERM. Then Run returns a *SignalError, which
+	// wraps whatever the command returned.
+	//
+	// After the first signal, the command has until the GracePeriod expires to
+	// return. Receiving a second signal, or the expiry of the GracePeriod,
+	// exits the program immediately with the status 128 plus the signal
+	// number.
+	Signals []os.Signal
+	// GracePeriod is how long to wait for the command to return after a
+	// signal, see Signals. If it's zero, then wait until a second signal.
+	GracePeriod time.Duration
+	// In, Out and Err optionally replace os.Stdin, os.Stdout and os.Stderr
+	// for the whole tree. Commands get them with Stdin, Stdout and Stderr.
+	// When Err is set, it's the output of every flag set that Run parses, so
+	// help messages go there too. The previous outputs are put back when Run
+	// returns. Messages from alf itself, such as the errors
+	// reported by Main, go to Err, and completion candidates go to Out.
+	In  io.Reader
+	Out io.Writer
+	Err io.Writer
 }
 
 // Run parses the top-level flags, extracts the positional arguments and
 // executes the command. Invoke this from main with args as os.Args[1:].
+//
+// When the first arg is "__complete", Run does not execute anything. Instead it
+// outputs shell completion candidates for the remaining args, one per line. The
+// last arg is the word being completed. The scripts from WriteCompletion call
+// back into the program this way.
 func (r *Root) Run(ctx context.Context, args []string) (err error) {
-	if err = r.Flags.Parse(args); err != nil {
+	if len(args) > 0 && args[0] == completeCmd {
+		return r.complete(ctx, r.stdout(), args[1:])
+	}
+	if len(r.Signals) > 0 {
+		var stop func() os.Signal
+		ctx, stop = r.notifySignals(ctx)
+		defer func() {
+			if sig := stop(); sig != nil {
+				err = &SignalError{Signal: sig, Err: err}
+			}
+		}()
+	}
+	fr := &frame{root: r, prog: r.name(), trace: traceFrom(ctx), run: &runState{args: args}}
+	ctx = withFrame(ctx, fr)
+	defer fr.run.restoreOutputs()
+	if r.RecoverPanics {
+		defer r.recoverPanic(fr, &err)
+	}
+	if err = fr.parse(r.Delegator, r.Flags, args); err != nil {
+		return
+	}
+	if r.LoadConfig != nil {
+		if fr.config, err = r.LoadConfig(ctx); err != nil {
+			return
+		}
+		if err = validateConfig(r, fr.config); err != nil {
+			return
+		}
+		// The top-level flags were parsed before there was a Config.
+		if err = applyConfig(fr, r.Flags); err != nil {
+			return
+		}
+	}
+	// Required flags are checked once the Config has had a chance to set them.
+	if err = fr.checkConstraints(fr.requiredFlags(r.Flags, false), r.Flags); err != nil {
+		fr.maybeCallUsage(err, r.Flags)
 		return
 	}
 	if r.PrePerform != nil {
 		err = r.PrePerform(ctx)
 		if errors.Is(err, ErrShowUsage) {
 			r.Flags.Usage()
+			fr.traceUsageShown()
 		}
 		if err != nil {
 			return
@@ -39,6 +146,15 @@ func (r *Root) Run(ctx context.Context, args []string) (err error) {
 	return
 }
 
+// name is the program name. It's derived from the name of the root flag set,
+// falling back to the name of the running binary.
+func (r *Root) name() string {
+	if r.Flags != nil && r.Flags.Name() != "" {
+		return filepath.Base(r.Flags.Name())
+	}
+	return filepath.Base(os.Args[0])
+}
+
 // Directive is an abstraction for a parent or child command. A parent would
 // delegate to a subcommand, while a subcommand does the actual task.
 type Directive interface {
@@ -54,16 +170,22 @@ type Directive interface {
 // message if you're use error wrapping.
 var ErrShowUsage = errors.New("")
 
-func maybeCallUsage(err error, flags *flag.FlagSet) {
+func (fr *frame) maybeCallUsage(err error, flags *flag.FlagSet) {
 	if err == nil {
 		return
 	}
 	for _, target := range []error{ErrShowUsage, flag.ErrHelp, errUnknownCommand} {
 		if errors.Is(err, target) {
 			flags.Usage()
-			return
+			fr.traceUsageShown()
+			break
 		}
 	}
+
+	var unknown *UnknownCommandError
+	if errors.As(err, &unknown) && len(unknown.Suggestions) > 0 {
+		fmt.Fprintf(flags.Output(), "\nDid you mean this?\n\t%s\n", strings.Join(unknown.Suggestions, "\n\t"))
+	}
 }
 
 var errUnknownCommand = errors.New("unknown command")
@@ -324,6 +324,179 @@ func TestDelegator(t *testing.T) {
 		}
 	})
 
+	t.Run("Aliases", func(t *testing.T) {
+		var called string
+		newCommand := func(name string) *alf.Command {
+			return &alf.Command{
+				Description: name,
+				Setup:       func(p flag.FlagSet) *flag.FlagSet { return newMutedFlagSet(name, flag.ContinueOnError) },
+				Run:         func(ctx context.Context) error { called = name; return nil },
+			}
+		}
+		del := alf.Delegator{
+			Description: "root",
+			Flags:       newMutedFlagSet("root", flag.ContinueOnError),
+			Subs: map[string]alf.Directive{
+				"list":   newCommand("list"),
+				"remove": newCommand("remove"),
+				"rm":     newCommand("rm"),
+			},
+			Aliases: map[string]string{"ls": "list", "l": "list", "rm": "remove", "del": "remove", "x": "nope"},
+		}
+		root := alf.Root{Delegator: &del}
+
+		for _, test := range []struct {
+			arg, exp string
+			expErr   bool
+		}{
+			{arg: "list", exp: "list"},
+			{arg: "ls", exp: "list"},
+			{arg: "l", exp: "list"},
+			{arg: "del", exp: "remove"},
+			{arg: "rm", exp: "rm"}, // the name in Subs takes precedence.
+			{arg: "x", expErr: true},
+		} {
+			called = ""
+			err := root.Run(context.TODO(), []string{test.arg})
+			if err != nil && !test.expErr {
+				t.Errorf("arg %q; unexpected error %v", test.arg, err)
+			} else if err == nil && test.expErr {
+				t.Errorf("arg %q; expected error", test.arg)
+			}
+			if called != test.exp {
+				t.Errorf("arg %q; called %q, expected %q", test.arg, called, test.exp)
+			}
+		}
+
+		if got := strings.Join(del.AliasesOf("list"), " "); got != "l ls" {
+			t.Errorf("AliasesOf; got %q, expected %q", got, "l ls")
+		}
+		if got := strings.Join(del.AliasesOf("remove"), " "); got != "del" {
+			t.Errorf("AliasesOf should leave out a shadowed alias; got %q, expected %q", got, "del")
+		}
+		out := del.DescribeSubcommands()
+		if len(out) != 3 {
+			t.Fatalf("wrong output length; got %d, expected %d", len(out), 3)
+		}
+		for i, expected := range []string{"list, l, ls ", "remove, del ", "rm "} {
+			if !strings.HasPrefix(out[i], expected) {
+				t.Errorf("item %d; got %q, expected prefix %q", i, out[i], expected)
+			}
+		}
+	})
+
+	t.Run("PrefixMatching", func(t *testing.T) {
+		var called string
+		newCommand := func(name string) *alf.Command {
+			return &alf.Command{
+				Description: name,
+				Setup:       func(p flag.FlagSet) *flag.FlagSet { return newMutedFlagSet(name, flag.ContinueOnError) },
+				Run:         func(ctx context.Context) error { called = name; return nil },
+			}
+		}
+		newRoot := func(onRoot, onNested bool) alf.Root {
+			nested := alf.Delegator{
+				Description: "nested",
+				Flags:       newMutedFlagSet("nested", flag.ContinueOnError),
+				Subs: map[string]alf.Directive{
+					"alfa":  newCommand("alfa"),
+					"bravo": newCommand("bravo"),
+					"bring": newCommand("bring"),
+				},
+				PrefixMatching: onNested,
+			}
+			return alf.Root{Delegator: &alf.Delegator{
+				Description: "root",
+				Flags:       newMutedFlagSet("root", flag.ContinueOnError),
+				Subs: map[string]alf.Directive{
+					"nested": &nested,
+					"nest":   newCommand("nest"),
+					"status": newCommand("status"),
+				},
+				Aliases:        map[string]string{"stat": "status"},
+				PrefixMatching: onRoot,
+			}}
+		}
+
+		for _, test := range []struct {
+			onRoot, onNested bool
+			args             []string
+			exp              string
+			expCandidates    []string
+		}{
+			{onRoot: true, args: []string{"nest"}, exp: "nest"},
+			{onRoot: true, args: []string{"nested", "a"}, exp: "alfa"},
+			{onRoot: true, args: []string{"neste", "a"}, exp: "alfa"},
+			{onRoot: true, args: []string{"neste", "bra"}, exp: "bravo"},
+			{onRoot: true, args: []string{"st"}, exp: "status"},
+			{onRoot: true, args: []string{"ne"}, expCandidates: []string{"nest", "nested"}},
+			{onRoot: true, args: []string{"nested", "br"}, expCandidates: []string{"bravo", "bring"}},
+			{onNested: true, args: []string{"nested", "a"}, exp: "alfa"},
+			{onNested: true, args: []string{"neste", "a"}},
+			{args: []string{"nested", "a"}},
+		} {
+			called = ""
+			root := newRoot(test.onRoot, test.onNested)
+			err := root.Run(context.TODO(), test.args)
+			if test.exp != "" && err != nil {
+				t.Errorf("args %q; unexpected error %v", test.args, err)
+			} else if test.exp == "" && err == nil {
+				t.Errorf("args %q; expected error", test.args)
+			}
+			if called != test.exp {
+				t.Errorf("args %q; called %q, expected %q", test.args, called, test.exp)
+			}
+
+			var ambiguous *alf.AmbiguousCommandError
+			if errors.As(err, &ambiguous) != (test.expCandidates != nil) {
+				t.Errorf("args %q; wrong error %v", test.args, err)
+			} else if ambiguous != nil && strings.Join(ambiguous.Candidates, " ") != strings.Join(test.expCandidates, " ") {
+				t.Errorf("args %q; got candidates %q, expected %q", test.args, ambiguous.Candidates, test.expCandidates)
+			}
+		}
+	})
+
+	t.Run("Suggestions", func(t *testing.T) {
+		for _, test := range []struct {
+			args   []string
+			exp    []string
+			expMsg string
+		}{
+			{args: []string{"delta", "hotl"}, exp: []string{"hotel"}, expMsg: `unknown command "hotl", did you mean "hotel"?`},
+			{args: []string{"delta", "ehco"}, exp: []string{"echo"}},
+			{args: []string{"delta", "indai"}, exp: []string{"india"}},
+			{args: []string{"delta", "f"}, exp: []string{"foxtrot"}},
+			{args: []string{"detla"}, exp: []string{"delta"}},
+			{args: []string{"xravo"}, exp: []string{"bravo"}},
+			{args: []string{"brvo"}, exp: []string{"bravo"}},
+			{args: []string{"zzzzzz"}, exp: []string{}, expMsg: `unknown command "zzzzzz"`},
+		} {
+			root := newStubRoot("stub", nil, nil)
+			var out bytes.Buffer
+			root.Flags.SetOutput(&out)
+			root.Flags.Usage = func() {}
+			root.Subs["delta"].(*alf.Delegator).Flags.SetOutput(&out)
+			root.Subs["delta"].(*alf.Delegator).Flags.Usage = func() {}
+			root.Aliases = map[string]string{"b": "bravo", "xray": "bravo"}
+
+			err := root.Run(context.TODO(), test.args)
+			var unknown *alf.UnknownCommandError
+			if !errors.As(err, &unknown) {
+				t.Errorf("args %q; expected %T, got %v", test.args, unknown, err)
+				continue
+			}
+			if strings.Join(unknown.Suggestions, " ") != strings.Join(test.exp, " ") {
+				t.Errorf("args %q; got suggestions %q, expected %q", test.args, unknown.Suggestions, test.exp)
+			}
+			if test.expMsg != "" && err.Error() != test.expMsg {
+				t.Errorf("args %q; got message %q, expected %q", test.args, err.Error(), test.expMsg)
+			}
+			if mentioned := strings.Contains(out.String(), "Did you mean this?"); mentioned != (len(test.exp) > 0) {
+				t.Errorf("args %q; wrong help output %q", test.args, out.String())
+			}
+		}
+	})
+
 	// Tests that a Delegator with Subs can pass flags from a parent to child in
 	// various ways.
 	t.Run("sharing flag data", func(t *testing.T) {
@@ -0,0 +1,218 @@
+// Package alftest runs alf command trees in tests. It's an alternative to
+// muting flag sets and counting calls to Usage funcs by hand.
+//
+//	func TestCities(t *testing.T) {
+//		res := alftest.Run(t, newRoot(), alftest.Input{Args: []string{"bar", "cities", "-bravo"}})
+//		res.AssertNoError(t)
+//		res.AssertPath(t, "bar", "cities")
+//		res.AssertNoUsage(t)
+//		alftest.AssertGolden(t, "cities_bravo", res.Stdout)
+//	}
+//
+// Run sets the In, Out and Err streams of the Root while it runs, so commands
+// should use alf.Stdin, alf.Stdout and alf.Stderr rather than the files in the
+// os package. Every flag set that's parsed outputs to Err, so help messages are
+// captured too. Flag sets should be made with flag.ContinueOnError, because the
+// other options exit or panic on a parsing error.
+package alftest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+// UpdateGoldenEnv is the name of an environment variable. When it's set to a
+// non-empty value, AssertGolden writes golden files rather than comparing them,
+// as in:
+//
+//	UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+// Input is what to run a Root with.
+type Input struct {
+	// Args are the command line arguments, not including the program name.
+	Args []string
+	// Env has environment variables to set while the Root runs. They're set
+	// with the Setenv method of testing.TB, so they're restored when the test
+	// ends.
+	Env map[string]string
+	// Stdin is the standard input.
+	Stdin string
+	// Context is the context to pass to Root.Run. If it's nil, then it's
+	// context.Background.
+	Context context.Context
+}
+
+// Result describes what happened when a Root ran.
+type Result struct {
+	// Err is what Root.Run returned.
+	Err error
+	// Stdout is the standard output.
+	Stdout string
+	// Stderr is the standard error, which includes help messages.
+	Stderr string
+	// Path is the names of the subcommands that were selected, not including
+	// the program name, such as ["bar", "cities"]. It's empty if no
+	// subcommand was selected.
+	Path []string
+	// UsageShown tells whether a Usage func was invoked, by alf or by the flag
+	// package.
+	UsageShown bool
+	// UsagePath is the level of the command path whose usage was shown, in the
+	// same format as Path. If it was shown more than once, then it's the last
+	// one.
+	UsagePath []string
+}
+
+// Run runs the Root with the Input and captures what happened. Afterwards, the
+// streams of the Root are put back the way they were.
+func Run(t testing.TB, root *alf.Root, in Input) *Result {
+	t.Helper()
+
+	for key, val := range in.Env {
+		t.Setenv(key, val)
+	}
+	ctx := in.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	res := &Result{Path: []string{}}
+	ctx = alf.WithTrace(ctx, &alf.Trace{
+		CommandSelected: func(path []string) { res.Path = path },
+		UsageShown: func(path []string) {
+			res.UsageShown = true
+			res.UsagePath = path
+		},
+	})
+
+	var stdout, stderr bytes.Buffer
+	origIn, origOut, origErr := root.In, root.Out, root.Err
+	root.In, root.Out, root.Err = strings.NewReader(in.Stdin), &stdout, &stderr
+	defer func() {
+		root.In, root.Out, root.Err = origIn, origOut, origErr
+		res.Stdout, res.Stderr = stdout.String(), stderr.String()
+	}()
+
+	res.Err = root.Run(ctx, in.Args)
+	return res
+}
+
+// AssertNoError fails the test if Root.Run returned an error.
+func (r *Result) AssertNoError(t testing.TB) {
+	t.Helper()
+	if r.Err != nil {
+		t.Errorf("expected no error, got %v", r.Err)
+	}
+}
+
+// AssertError fails the test unless Root.Run returned an error that matches
+// target, according to errors.Is.
+func (r *Result) AssertError(t testing.TB, target error) {
+	t.Helper()
+	if !errors.Is(r.Err, target) {
+		t.Errorf("expected error %v, got %v", target, r.Err)
+	}
+}
+
+// AssertErrorContains fails the test unless Root.Run returned an error with a
+// message that contains msg.
+func (r *Result) AssertErrorContains(t testing.TB, msg string) {
+	t.Helper()
+	if r.Err == nil {
+		t.Errorf("expected an error containing %q, got none", msg)
+	} else if !strings.Contains(r.Err.Error(), msg) {
+		t.Errorf("expected an error containing %q, got %q", msg, r.Err)
+	}
+}
+
+// AssertPath fails the test unless the subcommands in path were selected.
+// Leave out path to check that none were.
+func (r *Result) AssertPath(t testing.TB, path ...string) {
+	t.Helper()
+	if path == nil {
+		path = []string{}
+	}
+	if !reflect.DeepEqual(r.Path, path) {
+		t.Errorf("wrong command path; got %q, expected %q", r.Path, path)
+	}
+}
+
+// AssertUsage fails the test unless the usage was shown for the level at path.
+// Leave out path for the top level.
+func (r *Result) AssertUsage(t testing.TB, path ...string) {
+	t.Helper()
+	if !r.UsageShown {
+		t.Errorf("expected usage of %q to be shown, it wasn't", path)
+		return
+	}
+	if path == nil {
+		path = []string{}
+	}
+	if !reflect.DeepEqual(r.UsagePath, path) {
+		t.Errorf("wrong usage shown; got %q, expected %q", r.UsagePath, path)
+	}
+}
+
+// AssertNoUsage fails the test if any usage was shown.
+func (r *Result) AssertNoUsage(t testing.TB) {
+	t.Helper()
+	if r.UsageShown {
+		t.Errorf("expected no usage to be shown, got usage of %q", r.UsagePath)
+	}
+}
+
+// AssertStdout fails the test unless the standard output contains each of the
+// strings.
+func (r *Result) AssertStdout(t testing.TB, contains ...string) {
+	t.Helper()
+	assertContains(t, "stdout", r.Stdout, contains)
+}
+
+// AssertStderr fails the test unless the standard error contains each of the
+// strings.
+func (r *Result) AssertStderr(t testing.TB, contains ...string) {
+	t.Helper()
+	assertContains(t, "stderr", r.Stderr, contains)
+}
+
+func assertContains(t testing.TB, name, got string, contains []string) {
+	t.Helper()
+	for _, want := range contains {
+		if !strings.Contains(got, want) {
+			t.Errorf("%s should contain %q, got:\n%s", name, want, got)
+		}
+	}
+}
+
+// AssertGolden compares got to the golden file testdata/<name>.golden, and
+// fails the test if they're different. When the environment variable named by
+// UpdateGoldenEnv is set, it writes got to the file instead.
+func AssertGolden(t testing.TB, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; run with %s=1 to create it", err, UpdateGoldenEnv)
+	}
+	if got != string(want) {
+		t.Errorf("output doesn't match %s; run with %s=1 to update it\ngot:\n%s\nexpected:\n%s", path, UpdateGoldenEnv, got, want)
+	}
+}
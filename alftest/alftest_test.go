@@ -0,0 +1,148 @@
+package alftest_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+	"github.com/rafaelespinoza/alf/alftest"
+)
+
+var errStub = errors.New("oof")
+
+func newRoot() *alf.Root {
+	var (
+		verbose bool
+		name    string
+	)
+	nested := &alf.Delegator{
+		Description: "has subcommands",
+		Flags:       flag.NewFlagSet("stub nested", flag.ContinueOnError),
+		Subs: map[string]alf.Directive{
+			"echo": &alf.Command{
+				Description: "copy stdin to stdout",
+				Setup: func(p flag.FlagSet) *flag.FlagSet {
+					p.Init("stub nested echo", flag.ContinueOnError)
+					p.StringVar(&name, "name", "anon", "who is echoing")
+					return &p
+				},
+				Run: func(ctx context.Context) error {
+					in, err := io.ReadAll(alf.Stdin(ctx))
+					if err != nil {
+						return err
+					}
+					fmt.Fprintf(alf.Stdout(ctx), "%s says %s", name, in)
+					return nil
+				},
+			},
+			"fail": &alf.Command{
+				Description: "return an error",
+				Setup: func(p flag.FlagSet) *flag.FlagSet {
+					p.Init("stub nested fail", flag.ContinueOnError)
+					return &p
+				},
+				Run: func(ctx context.Context) error {
+					return fmt.Errorf("%w%w", errStub, alf.ErrShowUsage)
+				},
+			},
+		},
+	}
+	root := &alf.Root{
+		Delegator: &alf.Delegator{
+			Description: "stub",
+			Flags:       flag.NewFlagSet("stub", flag.ContinueOnError),
+			Subs:        map[string]alf.Directive{"nested": nested},
+		},
+		EnvPrefix: "STUB",
+	}
+	root.Flags.BoolVar(&verbose, "verbose", false, "say more")
+	return root
+}
+
+func TestRun(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		res := alftest.Run(t, newRoot(), alftest.Input{
+			Args:  []string{"nested", "echo"},
+			Env:   map[string]string{"STUB_NESTED_ECHO_NAME": "bob"},
+			Stdin: "hello\n",
+		})
+		res.AssertNoError(t)
+		res.AssertPath(t, "nested", "echo")
+		res.AssertNoUsage(t)
+		res.AssertStdout(t, "bob says hello")
+		if res.Stderr != "" {
+			t.Errorf("expected empty stderr, got %q", res.Stderr)
+		}
+	})
+
+	t.Run("help", func(t *testing.T) {
+		res := alftest.Run(t, newRoot(), alftest.Input{Args: []string{"nested", "echo", "-h"}})
+		res.AssertError(t, flag.ErrHelp)
+		res.AssertPath(t, "nested", "echo")
+		res.AssertUsage(t, "nested", "echo")
+		alftest.AssertGolden(t, "help", res.Stderr)
+	})
+
+	t.Run("usage from error", func(t *testing.T) {
+		res := alftest.Run(t, newRoot(), alftest.Input{Args: []string{"nested", "fail"}})
+		res.AssertError(t, errStub)
+		res.AssertErrorContains(t, "oof")
+		res.AssertUsage(t, "nested", "fail")
+		res.AssertStderr(t, "Usage:", "stub nested fail")
+	})
+
+	t.Run("unknown command", func(t *testing.T) {
+		res := alftest.Run(t, newRoot(), alftest.Input{Args: []string{"nest"}})
+		res.AssertErrorContains(t, `unknown command "nest"`)
+		res.AssertPath(t)
+		res.AssertUsage(t)
+		res.AssertStderr(t, "Did you mean this?", "nested")
+	})
+
+	t.Run("missing subcommand", func(t *testing.T) {
+		res := alftest.Run(t, newRoot(), alftest.Input{Args: []string{"nested"}})
+		res.AssertError(t, flag.ErrHelp)
+		res.AssertPath(t, "nested")
+		res.AssertUsage(t, "nested")
+	})
+
+	t.Run("parse error", func(t *testing.T) {
+		res := alftest.Run(t, newRoot(), alftest.Input{Args: []string{"-nope"}})
+		res.AssertErrorContains(t, "flag provided but not defined: -nope")
+		res.AssertUsage(t)
+		if !strings.HasPrefix(res.Stderr, "flag provided but not defined") {
+			t.Errorf("wrong stderr; got %q", res.Stderr)
+		}
+	})
+}
+
+func TestAssertGolden(t *testing.T) {
+	t.Setenv(alftest.UpdateGoldenEnv, "")
+	alftest.AssertGolden(t, "golden", "some output\n")
+
+	dir := t.TempDir()
+	orig, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(orig) }()
+
+	t.Setenv(alftest.UpdateGoldenEnv, "1")
+	alftest.AssertGolden(t, "new", "fresh output\n")
+	got, err := os.ReadFile("testdata/new.golden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "fresh output\n" {
+		t.Errorf("wrong golden file; got %q", got)
+	}
+}
@@ -0,0 +1,116 @@
+package alf
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Arg describes a positional argument of a Command. See Command.Args.
+type Arg struct {
+	// Name is what to call the argument in help messages and errors.
+	Name string
+	// Description is an optional explanation of the argument, for help.
+	Description string
+	// Optional means the argument may be left out. Only the last arguments
+	// can be optional.
+	Optional bool
+	// Variadic means the argument takes the rest of the positional arguments.
+	// Only the last argument can be variadic. Unless it's also Optional, it
+	// needs at least one value.
+	Variadic bool
+	// Value optionally parses and stores the argument, like the Value of a
+	// flag. Its Set method is called with the argument, or with each one if
+	// it's Variadic. An error from Set is reported like an arity error.
+	Value flag.Value
+}
+
+// String is how the argument appears in a synopsis, such as "<country>" or
+// "[city...]".
+func (a Arg) String() string {
+	name := a.Name
+	if a.Variadic {
+		name += "..."
+	}
+	if a.Optional {
+		return "[" + name + "]"
+	}
+	return "<" + a.Name + ">" + strings.TrimPrefix(name, a.Name)
+}
+
+// ArgError is returned when a Command's positional arguments don't match its
+// Args. It wraps ErrShowUsage, so the Command's usage is shown.
+type ArgError struct {
+	// Arg is the argument with the problem. It's nil when there are too many
+	// arguments.
+	Arg *Arg
+	// Msg describes the problem.
+	Msg string
+}
+
+func (e *ArgError) Error() string { return e.Msg }
+
+func (e *ArgError) Unwrap() error { return ErrShowUsage }
+
+// argsSynopsis outputs the arguments the way they'd appear in a usage line, for
+// example "<country> [city...]".
+func argsSynopsis(args []Arg) string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = arg.String()
+	}
+	return strings.Join(out, " ")
+}
+
+// validateArgSpecs checks that the specs make sense: only the last arguments
+// may be optional, and only the last one may be variadic.
+func validateArgSpecs(specs []Arg) error {
+	var optional bool
+	for i, spec := range specs {
+		if spec.Variadic && i < len(specs)-1 {
+			return fmt.Errorf("argument %s is variadic but it's not the last one", spec)
+		}
+		if optional && !spec.Optional {
+			return fmt.Errorf("argument %s is required but it comes after an optional one", spec)
+		}
+		optional = optional || spec.Optional
+	}
+	return nil
+}
+
+// checkArgs validates the number of positional arguments, then passes each one
+// to the Value of its spec. Nil specs mean anything goes, while empty specs
+// mean there should be no positional arguments.
+func checkArgs(specs []Arg, positionals []string) error {
+	if specs == nil {
+		return nil
+	}
+	if err := validateArgSpecs(specs); err != nil {
+		return err
+	}
+
+	for i := range specs {
+		if spec := &specs[i]; i >= len(positionals) && !spec.Optional {
+			return &ArgError{Arg: spec, Msg: fmt.Sprintf("missing argument %s", spec)}
+		}
+	}
+	if n := len(specs); (n < 1 || !specs[n-1].Variadic) && len(positionals) > n {
+		return &ArgError{Msg: fmt.Sprintf(
+			"too many arguments, expected at most %d but got %d", n, len(positionals),
+		)}
+	}
+
+	for i, val := range positionals {
+		spec := &specs[len(specs)-1] // the variadic one
+		if i < len(specs) {
+			spec = &specs[i]
+		}
+		if spec.Value == nil {
+			continue
+		}
+		if err := spec.Value.Set(val); err != nil {
+			return &ArgError{Arg: spec, Msg: fmt.Sprintf("invalid value %q for argument %s: %v", val, spec, err)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package alf_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+type stubIntArg int
+
+func (a *stubIntArg) String() string { return strconv.Itoa(int(*a)) }
+func (a *stubIntArg) Set(v string) (err error) {
+	n, err := strconv.Atoi(v)
+	*a = stubIntArg(n)
+	return
+}
+
+func TestArgs(t *testing.T) {
+	newRoot := func(out *bytes.Buffer, ran *bool, args []alf.Arg) *alf.Root {
+		return &alf.Root{
+			Delegator: &alf.Delegator{
+				Description: "root",
+				Flags:       newMutedFlagSet("root", flag.ContinueOnError),
+				Subs: map[string]alf.Directive{
+					"cities": &alf.Command{
+						Description: "takes positional args",
+						Setup: func(p flag.FlagSet) *flag.FlagSet {
+							f := flag.NewFlagSet("cities", flag.ContinueOnError)
+							f.SetOutput(out)
+							return f
+						},
+						Run: func(ctx context.Context) error {
+							*ran = true
+							return nil
+						},
+						Args: args,
+					},
+				},
+			},
+		}
+	}
+
+	var (
+		country stubCompleter
+		cities  stubCompleter
+		count   stubIntArg
+	)
+	specs := []alf.Arg{
+		{Name: "country", Description: "where to look", Value: &country},
+		{Name: "city", Description: "cities to show", Optional: true, Variadic: true, Value: &cities},
+	}
+
+	tests := []struct {
+		name       string
+		specs      []alf.Arg
+		args       []string
+		expErr     string
+		expCountry []string
+		expCities  []string
+	}{
+		{name: "nil specs allow anything", args: []string{"a", "b"}},
+		{name: "empty specs allow nothing", specs: []alf.Arg{}, args: []string{"a"}, expErr: "too many arguments, expected at most 0 but got 1"},
+		{name: "required", specs: specs[:1], args: []string{"ar"}, expCountry: []string{"ar"}},
+		{name: "missing", specs: specs[:1], args: []string{}, expErr: "missing argument <country>"},
+		{name: "too many", specs: specs[:1], args: []string{"ar", "bo"}, expErr: "too many arguments, expected at most 1 but got 2"},
+		{name: "optional variadic left out", specs: specs, args: []string{"ar"}, expCountry: []string{"ar"}},
+		{name: "variadic", specs: specs, args: []string{"ar", "x", "y"}, expCountry: []string{"ar"}, expCities: []string{"x", "y"}},
+		{
+			name:   "required variadic",
+			specs:  []alf.Arg{{Name: "city", Variadic: true}},
+			args:   []string{},
+			expErr: "missing argument <city>...",
+		},
+		{
+			name:   "invalid value",
+			specs:  []alf.Arg{{Name: "count", Value: &count}},
+			args:   []string{"ten"},
+			expErr: `invalid value "ten" for argument <count>`,
+		},
+		{
+			name:   "variadic must be last",
+			specs:  []alf.Arg{{Name: "city", Variadic: true}, {Name: "country"}},
+			args:   []string{"x", "y"},
+			expErr: "argument <city>... is variadic but it's not the last one",
+		},
+		{
+			name:   "required after optional",
+			specs:  []alf.Arg{{Name: "city", Optional: true}, {Name: "country"}},
+			args:   []string{"x", "y"},
+			expErr: "argument <country> is required but it comes after an optional one",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			country, cities = nil, nil
+			var out bytes.Buffer
+			var ran bool
+			err := newRoot(&out, &ran, test.specs).Run(context.Background(), append([]string{"cities"}, test.args...))
+			if test.expErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				if !ran {
+					t.Error("command should run")
+				}
+			} else if err == nil || !strings.Contains(err.Error(), test.expErr) {
+				t.Fatalf("expected error with %q, got %v", test.expErr, err)
+			} else if ran {
+				t.Error("command should not run")
+			}
+			if got := strings.Join(country, " "); got != strings.Join(test.expCountry, " ") {
+				t.Errorf("wrong country; got %q, expected %q", country, test.expCountry)
+			}
+			if got := strings.Join(cities, " "); got != strings.Join(test.expCities, " ") {
+				t.Errorf("wrong cities; got %q, expected %q", cities, test.expCities)
+			}
+
+			var aerr *alf.ArgError
+			if errors.As(err, &aerr) && !strings.Contains(out.String(), "Usage:") {
+				t.Error("should show usage")
+			}
+		})
+	}
+
+	t.Run("help", func(t *testing.T) {
+		var out bytes.Buffer
+		newRoot(&out, new(bool), specs).Run(context.Background(), []string{"cities", "-h"})
+		for _, mention := range []string{
+			"root cities [flags] <country> [city...]\n",
+			"Arguments:\n\n\tcountry             \twhere to look\n\tcity                \tcities to show\n",
+		} {
+			if !strings.Contains(out.String(), mention) {
+				t.Errorf("help does not contain %q\n%s", mention, out.String())
+			}
+		}
+	})
+
+	t.Run("synopsis", func(t *testing.T) {
+		for _, test := range []struct {
+			arg alf.Arg
+			exp string
+		}{
+			{alf.Arg{Name: "a"}, "<a>"},
+			{alf.Arg{Name: "a", Optional: true}, "[a]"},
+			{alf.Arg{Name: "a", Variadic: true}, "<a>..."},
+			{alf.Arg{Name: "a", Optional: true, Variadic: true}, "[a...]"},
+		} {
+			if got := test.arg.String(); got != test.exp {
+				t.Errorf("got %q, expected %q", got, test.exp)
+			}
+		}
+	})
+}
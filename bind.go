@@ -0,0 +1,275 @@
+package alf
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bind defines a flag for each tagged field of the struct that dst points to.
+// It's an alternative to calling a method like (*flag.FlagSet).IntVar for each
+// flag, so it works anywhere a flag set is made, such as in a Command's Setup
+// func or for a Delegator's Flags. For example,
+//
+//	var args struct {
+//		Alpha int    `flag:"alpha" default:"42" usage:"a number"`
+//		Mode  string `flag:"mode" default:"fast" choices:"fast,slow" usage:"how to run"`
+//		DB    struct {
+//			Host string `flag:"host" env:"DB_HOST" required:"true" usage:"database host"`
+//		} `flag:"db"`
+//	}
+//	alf.Bind(flags, &args)
+//
+// defines the flags -alpha, -mode and -db-host. These are the tags:
+//
+//   - flag is the name of the flag. Fields without it are left alone, "-" also
+//     leaves a field alone.
+//   - default is the default value, in the same format as the command line.
+//     Lists and maps are comma-separated, as in "a,b" or "k1=v1,k2=v2".
+//   - usage is the usage message for help.
+//   - choices limits a string flag to a comma-separated list of values, like
+//     EnumVar.
+//   - env names an environment variable that can set the flag, as with
+//     Root.EnvPrefix. It's used as is, it takes precedence over a name derived
+//     from Root.EnvPrefix and it works without one.
+//   - required makes the flag mandatory, like a Required constraint, when it's
+//     "true". It's checked at the level of the command tree that defines the
+//     flag, right after the flags there are parsed, for a Root, Delegator or
+//     Command alike. A persistent flag, see Delegator.PersistentFlags, is
+//     checked before the Command at the end of the path runs instead.
+//
+// A field that's a struct, and not a value like time.Time, is nested: its
+// fields are bound too. If it has a flag tag, then that is a prefix of the
+// nested flag names, joined by a "-".
+//
+// The fields can be a bool, string, int, int64, uint, uint64, float64,
+// time.Duration, any of the types of the XxxVar funcs in this package, such as
+// []string or ByteSize, or any type whose pointer implements flag.Value.
+//
+// It panics if dst isn't a pointer to a struct, if a field has an unsupported
+// type, or if a tag is invalid, as the flag package does for similar mistakes.
+func Bind(fs *flag.FlagSet, dst any) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("alf.Bind: destination should be a pointer to a struct, not %T", dst))
+	}
+	bindStruct(fs, v.Elem(), "")
+}
+
+// boundValue wraps the flag.Value of a flag defined by Bind, to keep the tags
+// that alf needs later on. It's only used for flags that have any of them.
+// Keeping them on the flag itself means that they go along with copies of the
+// flag set, see Command.Setup, and nothing else.
+type boundValue struct {
+	flag.Value
+	env      string
+	required bool
+}
+
+// String is nil-safe, because the flag package calls it on the zero value of
+// the type to tell whether a default is worth showing.
+func (v *boundValue) String() string {
+	if v.Value == nil {
+		return ""
+	}
+	return v.Value.String()
+}
+
+func (v *boundValue) Get() any {
+	if g, ok := v.Value.(flag.Getter); ok {
+		return g.Get()
+	}
+	return v.Value.String()
+}
+
+func (v *boundValue) IsBoolFlag() bool {
+	b, ok := v.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func (v *boundValue) Complete(ctx context.Context, args []string, toComplete string) []string {
+	if c, ok := v.Value.(Completer); ok {
+		return c.Complete(ctx, args, toComplete)
+	}
+	return nil
+}
+
+// bindingOf finds the tags of a flag, if it was defined by Bind with any.
+func bindingOf(f *flag.Flag) *boundValue {
+	out, _ := f.Value.(*boundValue)
+	return out
+}
+
+var (
+	flagValueType = reflect.TypeOf((*flag.Value)(nil)).Elem()
+	timeType      = reflect.TypeOf(time.Time{})
+	ipNetType     = reflect.TypeOf(net.IPNet{})
+)
+
+// isNested reports whether a field of type t has fields of its own to bind.
+func isNested(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && t != ipNetType && !reflect.PointerTo(t).Implements(flagValueType)
+}
+
+func bindStruct(fs *flag.FlagSet, v reflect.Value, prefix string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, tagged := field.Tag.Lookup("flag")
+		if name == "-" {
+			continue
+		}
+		if !field.IsExported() {
+			if tagged {
+				panic(fmt.Sprintf("alf.Bind: field %s.%s has a flag tag but it's not exported", t, field.Name))
+			}
+			continue
+		}
+		if isNested(field.Type) {
+			nestedPrefix := prefix
+			if name != "" {
+				nestedPrefix += name + "-"
+			}
+			bindStruct(fs, v.Field(i), nestedPrefix)
+			continue
+		}
+		if !tagged {
+			continue
+		}
+		if name == "" {
+			panic(fmt.Sprintf("alf.Bind: field %s.%s has an empty flag tag", t, field.Name))
+		}
+		bindField(fs, v.Field(i).Addr(), prefix+name, field)
+	}
+}
+
+// bindField defines one flag, p is a pointer to the field.
+func bindField(fs *flag.FlagSet, p reflect.Value, name string, field reflect.StructField) {
+	tag := field.Tag
+	def, hasDefault := tag.Lookup("default")
+	usage := tag.Get("usage")
+	fail := func(format string, args ...any) {
+		panic(fmt.Sprintf("alf.Bind: flag -%s: ", name) + fmt.Sprintf(format, args...))
+	}
+
+	choices, hasChoices := tag.Lookup("choices")
+	if hasChoices {
+		if _, ok := p.Interface().(*string); !ok {
+			fail("choices only work with a string, not %s", field.Type)
+		}
+	}
+
+	switch ptr := p.Interface().(type) {
+	case flag.Value:
+		fs.Var(ptr, name, usage)
+	case *string:
+		if hasChoices {
+			EnumVar(fs, ptr, name, "", splitList(choices), usage)
+		} else {
+			fs.StringVar(ptr, name, "", usage)
+		}
+	case *bool:
+		fs.BoolVar(ptr, name, false, usage)
+	case *int:
+		fs.IntVar(ptr, name, 0, usage)
+	case *int64:
+		fs.Int64Var(ptr, name, 0, usage)
+	case *uint:
+		fs.UintVar(ptr, name, 0, usage)
+	case *uint64:
+		fs.Uint64Var(ptr, name, 0, usage)
+	case *float64:
+		fs.Float64Var(ptr, name, 0, usage)
+	case *time.Duration:
+		fs.DurationVar(ptr, name, 0, usage)
+	case *[]string:
+		// The default is passed along, rather than set, so that specifying the
+		// flag replaces it.
+		StringsVar(fs, ptr, name, splitList(def), usage)
+		hasDefault = false
+	case *map[string]string:
+		value := make(map[string]string)
+		if def != "" {
+			if err := (&mapValue{p: &value}).Set(def); err != nil {
+				fail("invalid default %q: %v", def, err)
+			}
+		}
+		MapVar(fs, ptr, name, value, usage)
+		hasDefault = false
+	case *ByteSize:
+		ByteSizeVar(fs, ptr, name, 0, usage)
+	case *time.Time:
+		TimeVar(fs, ptr, name, time.Time{}, usage)
+	case **url.URL:
+		URLVar(fs, ptr, name, nil, usage)
+	case *net.IP:
+		IPVar(fs, ptr, name, nil, usage)
+	case *net.IPNet:
+		IPNetVar(fs, ptr, name, net.IPNet{}, usage)
+	case **regexp.Regexp:
+		RegexpVar(fs, ptr, name, nil, usage)
+	default:
+		fail("unsupported type %s", field.Type)
+	}
+
+	f := fs.Lookup(name)
+	if hasDefault {
+		if err := f.Value.Set(def); err != nil {
+			fail("invalid default %q: %v", def, err)
+		}
+		f.DefValue = f.Value.String()
+	}
+
+	b := boundValue{Value: f.Value, env: tag.Get("env")}
+	if val, ok := tag.Lookup("required"); ok {
+		var err error
+		if b.required, err = strconv.ParseBool(val); err != nil {
+			fail("invalid required tag %q", val)
+		}
+	}
+	if b.env != "" || b.required {
+		f.Value = &b
+	}
+}
+
+// requiredFlags makes a Required constraint for the flags defined by Bind with
+// a required tag. Inherited flags are left to the level that defines them,
+// except for persistent flags. Those can be specified anywhere along the path,
+// so they're left to the Command at the end of it. The output is empty if
+// there are none.
+func (fr *frame) requiredFlags(flags *flag.FlagSet, isCommand bool) []Constraint {
+	var names []string
+	flags.VisitAll(func(f *flag.Flag) {
+		b := bindingOf(f)
+		if b == nil || !b.required {
+			return
+		}
+		if persistent := fr.isPersistent(f); (persistent && isCommand) || (!persistent && !fr.inherited(f)) {
+			names = append(names, f.Name)
+		}
+	})
+	if len(names) < 1 {
+		return nil
+	}
+	sort.Strings(names)
+	return []Constraint{Required(names...)}
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	out := strings.Split(s, ",")
+	for i := range out {
+		out[i] = strings.TrimSpace(out[i])
+	}
+	return out
+}
@@ -0,0 +1,313 @@
+package alf_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+type bindLevel int
+
+func (l *bindLevel) String() string { return fmt.Sprint(int(*l)) }
+func (l *bindLevel) Set(v string) error {
+	switch v {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return errors.New("expected low or high")
+	}
+	return nil
+}
+
+type bindArgs struct {
+	Alpha   int               `flag:"alpha" default:"42" usage:"a number"`
+	Bravo   bool              `flag:"bravo" usage:"a switch"`
+	Mode    string            `flag:"mode" default:"fast" choices:"fast, slow" usage:"how to run"`
+	Wait    time.Duration     `flag:"wait" default:"1s"`
+	Tags    []string          `flag:"tag" default:"a,b"`
+	Labels  map[string]string `flag:"label" default:"team=core"`
+	Size    alf.ByteSize      `flag:"size" default:"1MiB"`
+	Level   bindLevel         `flag:"level" default:"low"`
+	Ignored string
+	Skipped string `flag:"-"`
+	DB      struct {
+		Host string `flag:"host" env:"STUB_DB_HOST" usage:"database host"`
+		Port uint   `flag:"port" default:"5432"`
+	} `flag:"db"`
+	Embedded struct {
+		Name string `flag:"name" required:"true"`
+	}
+}
+
+func TestBind(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		var args bindArgs
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		alf.Bind(flags, &args)
+
+		var names []string
+		flags.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+		expNames := []string{"alpha", "bravo", "db-host", "db-port", "label", "level", "mode", "name", "size", "tag", "wait"}
+		if !reflect.DeepEqual(names, expNames) {
+			t.Errorf("wrong flags; got %q, expected %q", names, expNames)
+		}
+
+		if err := flags.Parse(nil); err != nil {
+			t.Fatal(err)
+		}
+		if args.Alpha != 42 || args.Mode != "fast" || args.Wait != time.Second || args.Size != alf.MiB || args.Level != 1 || args.DB.Port != 5432 {
+			t.Errorf("wrong defaults; got %+v", args)
+		}
+		if !reflect.DeepEqual(args.Tags, []string{"a", "b"}) {
+			t.Errorf("wrong tags; got %q", args.Tags)
+		}
+		if !reflect.DeepEqual(args.Labels, map[string]string{"team": "core"}) {
+			t.Errorf("wrong labels; got %v", args.Labels)
+		}
+		if f := flags.Lookup("alpha"); f.DefValue != "42" || f.Usage != "a number" {
+			t.Errorf("wrong flag; got %+v", f)
+		}
+	})
+
+	t.Run("set", func(t *testing.T) {
+		var args bindArgs
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		alf.Bind(flags, &args)
+		err := flags.Parse([]string{
+			"-alpha", "1", "-bravo", "-mode", "slow", "-tag", "c", "-level", "high",
+			"-db-host", "example.com", "-db-port", "3306", "-name", "x",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if args.Alpha != 1 || !args.Bravo || args.Mode != "slow" || args.Level != 2 || args.DB.Host != "example.com" || args.DB.Port != 3306 || args.Embedded.Name != "x" {
+			t.Errorf("wrong values; got %+v", args)
+		}
+		if !reflect.DeepEqual(args.Tags, []string{"c"}) {
+			t.Errorf("specifying the flag should replace the default; got %q", args.Tags)
+		}
+	})
+
+	t.Run("invalid choice", func(t *testing.T) {
+		var args bindArgs
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		alf.Bind(flags, &args)
+		err := flags.Parse([]string{"-mode", "medium"})
+		if err == nil || !strings.Contains(err.Error(), "must be one of fast, slow") {
+			t.Errorf("wrong error; got %v", err)
+		}
+	})
+
+	t.Run("misuse", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			dst    any
+			expMsg string
+		}{
+			{name: "not a pointer", dst: bindArgs{}, expMsg: "should be a pointer to a struct"},
+			{name: "unsupported type", dst: &struct {
+				C chan int `flag:"c"`
+			}{}, expMsg: "flag -c: unsupported type chan int"},
+			{name: "invalid default", dst: &struct {
+				N int `flag:"n" default:"x"`
+			}{}, expMsg: `flag -n: invalid default "x"`},
+			{name: "choices on int", dst: &struct {
+				N int `flag:"n" choices:"1,2"`
+			}{}, expMsg: "choices only work with a string"},
+			{name: "invalid required", dst: &struct {
+				S string `flag:"s" required:"yes"`
+			}{}, expMsg: `invalid required tag "yes"`},
+			{name: "unexported", dst: &struct {
+				s string `flag:"s"`
+			}{}, expMsg: "not exported"},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				defer func() {
+					msg := fmt.Sprint(recover())
+					if !strings.Contains(msg, test.expMsg) {
+						t.Errorf("panic %q should contain %q", msg, test.expMsg)
+					}
+				}()
+				alf.Bind(flag.NewFlagSet("test", flag.ContinueOnError), test.dst)
+			})
+		}
+	})
+}
+
+func TestBindCommand(t *testing.T) {
+	newRoot := func(out *bytes.Buffer, args *bindArgs) *alf.Root {
+		return &alf.Root{
+			Delegator: &alf.Delegator{
+				Description: "root",
+				Flags:       newMutedFlagSet("stub", flag.ContinueOnError),
+				Subs: map[string]alf.Directive{
+					"bound": &alf.Command{
+						Description: "has bound flags",
+						Setup: func(p flag.FlagSet) *flag.FlagSet {
+							f := flag.NewFlagSet("bound", flag.ContinueOnError)
+							f.SetOutput(out)
+							alf.Bind(f, args)
+							return f
+						},
+						Run: func(ctx context.Context) error { return nil },
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("STUB_DB_HOST", "db.example.com")
+		var args bindArgs
+		if err := newRoot(&bytes.Buffer{}, &args).Run(context.Background(), []string{"bound", "-name", "x"}); err != nil {
+			t.Fatal(err)
+		}
+		if args.DB.Host != "db.example.com" {
+			t.Errorf("wrong host; got %q", args.DB.Host)
+		}
+	})
+
+	t.Run("required", func(t *testing.T) {
+		var (
+			args bindArgs
+			out  bytes.Buffer
+		)
+		err := newRoot(&out, &args).Run(context.Background(), []string{"bound"})
+		var cerr *alf.ConstraintError
+		if !errors.As(err, &cerr) {
+			t.Fatalf("expected a *ConstraintError, got %v", err)
+		}
+		if err.Error() != "-name is required" {
+			t.Errorf("wrong error; got %q", err)
+		}
+		for _, exp := range []string{"Constraints:", "-name is required", "STUB_DB_HOST", "(one of: fast, slow)", "-db-host string"} {
+			if !strings.Contains(out.String(), exp) {
+				t.Errorf("usage should contain %q\n%s", exp, out.String())
+			}
+		}
+	})
+	t.Run("tags stay with the flag", func(t *testing.T) {
+		t.Setenv("STUB_DB_HOST", "db.example.com")
+		var args bindArgs
+		alf.Bind(flag.NewFlagSet("bound", flag.ContinueOnError), &args)
+
+		// A flag set that's made by hand for the same fields has no tags.
+		root := &alf.Root{
+			Delegator: &alf.Delegator{
+				Description: "root",
+				Flags:       newMutedFlagSet("stub", flag.ContinueOnError),
+				Subs: map[string]alf.Directive{
+					"plain": &alf.Command{
+						Description: "has plain flags",
+						Setup: func(p flag.FlagSet) *flag.FlagSet {
+							p.Init("plain", flag.ContinueOnError)
+							p.SetOutput(io.Discard)
+							p.StringVar(&args.DB.Host, "host", "", "database host")
+							p.StringVar(&args.Embedded.Name, "name", "", "a name")
+							return &p
+						},
+						Run: func(ctx context.Context) error { return nil },
+					},
+				},
+			},
+		}
+		if err := root.Run(context.Background(), []string{"plain"}); err != nil {
+			t.Fatal(err)
+		}
+		if args.DB.Host != "" {
+			t.Errorf("the env tag shouldn't apply; got host %q", args.DB.Host)
+		}
+	})
+}
+
+func TestBindDelegator(t *testing.T) {
+	newRoot := func(out *bytes.Buffer) *alf.Root {
+		var rootArgs struct {
+			Token string `flag:"token" required:"true"`
+		}
+		var nestedArgs struct {
+			Zone string `flag:"zone" required:"true"`
+		}
+		var persistentArgs struct {
+			Region string `flag:"region" required:"true"`
+		}
+		nested := &alf.Delegator{
+			Description: "has required flags",
+			Flags:       flag.NewFlagSet("nested", flag.ContinueOnError),
+			Subs: map[string]alf.Directive{
+				"echo": &alf.Command{
+					Description: "reuse the parent's flags",
+					Setup: func(p flag.FlagSet) *flag.FlagSet {
+						p.Init("echo", flag.ContinueOnError)
+						return &p
+					},
+					Run: func(ctx context.Context) error { return nil },
+				},
+			},
+		}
+		nested.Flags.SetOutput(out)
+		alf.Bind(nested.Flags, &nestedArgs)
+		root := &alf.Root{
+			Delegator: &alf.Delegator{
+				Description:     "root",
+				Flags:           flag.NewFlagSet("stub", flag.ContinueOnError),
+				PersistentFlags: flag.NewFlagSet("stub", flag.ContinueOnError),
+				Subs:            map[string]alf.Directive{"nested": nested},
+			},
+		}
+		root.Flags.SetOutput(out)
+		alf.Bind(root.Flags, &rootArgs)
+		alf.Bind(root.PersistentFlags, &persistentArgs)
+		return root
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expErr   string
+		expUsage string
+	}{
+		{name: "root", args: []string{"nested", "echo"}, expErr: "-token is required", expUsage: "Usage:\n\n\tstub [flags] subcommand"},
+		{name: "nested", args: []string{"-token", "t", "nested", "echo"}, expErr: "-zone is required", expUsage: "Usage:\n\n\tstub nested [flags] subcommand"},
+		{name: "persistent", args: []string{"-token", "t", "nested", "-zone", "z", "echo"}, expErr: "-region is required", expUsage: "Usage:\n\n\tstub nested echo"},
+		{name: "persistent after the subcommand", args: []string{"-token", "t", "nested", "-zone", "z", "echo", "-region", "r"}},
+		{name: "persistent before the subcommand", args: []string{"-token", "t", "-region", "r", "nested", "-zone", "z", "echo"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := newRoot(&out).Run(context.Background(), test.args)
+			if test.expErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var cerr *alf.ConstraintError
+			if !errors.As(err, &cerr) {
+				t.Fatalf("expected a *ConstraintError, got %v", err)
+			}
+			if err.Error() != test.expErr {
+				t.Errorf("wrong error; got %q, expected %q", err, test.expErr)
+			}
+			for _, exp := range []string{test.expUsage, "Constraints:", test.expErr} {
+				if !strings.Contains(out.String(), exp) {
+					t.Errorf("usage should contain %q\n%s", exp, out.String())
+				}
+			}
+		})
+	}
+}
@@ -14,10 +14,40 @@ type Command struct {
 	// needed. You could also just allow the input flagset to pass through. If
 	// you don't want to share any flag data between parent and child, then
 	// create a new flag set.
+	//
+	// Setup may be called for Commands that don't run, because Walk calls it
+	// for every Command in the tree. So do WriteCompletion, WriteManPages,
+	// WriteMarkdown, and Run when Root.LoadConfig returns a non-empty Config.
+	// Avoid doing anything in Setup besides defining flags.
 	Setup func(parentFlags flag.FlagSet) *flag.FlagSet
 	// Run is a wrapper function that selects the necessary command line inputs,
 	// executes the command and returns any errors.
 	Run func(ctx context.Context) error
+	// Complete is an optional function to suggest values for a positional
+	// argument during shell completion. The args are the positional arguments
+	// preceding the one being completed, toComplete is what the user has typed
+	// so far. The Command's flags are parsed before this is called.
+	Complete func(ctx context.Context, args []string, toComplete string) []string
+	// Interspersed lets flags be mixed in with the positional arguments, like
+	// GNU-style programs. Normally, parsing stops at the first positional
+	// argument, so in "cities paris -bravo" the "-bravo" would be a positional
+	// argument. With this on, it's a flag. An argument of "--" ends the flags,
+	// everything after it is positional.
+	Interspersed bool
+	// Constraints are optional rules about which flags must be set, or can't
+	// be set together. They're checked after the flags are parsed and before
+	// Run. If any are broken, then all of the violations are reported together
+	// in a *ConstraintError and the usage is shown. The rules are listed in
+	// generated help messages. Flags defined by Bind with a required tag are
+	// checked too.
+	Constraints []Constraint
+	// Args optionally describes the positional arguments. When it's not nil,
+	// the number of positional arguments is checked after the flags are
+	// parsed and before Run. If it doesn't match, then Run returns an
+	// *ArgError and the usage is shown. Each argument may also be parsed into
+	// its Value. The arguments are shown in generated help messages, like
+	// "bin bar cities <country> [city...]".
+	Args []Arg
 
 	flags *flag.FlagSet
 }
@@ -27,3 +57,21 @@ func (c *Command) Summary() string { return c.Description }
 
 // Perform calls Run to execute the task at hand.
 func (c *Command) Perform(ctx context.Context) error { return c.Run(ctx) }
+
+// inspectFlags resolves the flag set that Setup would produce without touching
+// the parent flags. It's for tooling that needs to see a Command's flags
+// without running it.
+func (c *Command) inspectFlags(parentFlags *flag.FlagSet) *flag.FlagSet {
+	var flags *flag.FlagSet
+	if c.Setup != nil {
+		parent := flag.NewFlagSet("", flag.ContinueOnError)
+		if parentFlags != nil {
+			parent = cloneFlagSet(parentFlags)
+		}
+		flags = c.Setup(*parent)
+	}
+	if flags == nil {
+		flags = flag.NewFlagSet("", flag.ContinueOnError)
+	}
+	return flags
+}
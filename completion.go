@@ -0,0 +1,462 @@
+package alf
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Shells supported by WriteCompletion.
+const (
+	ShellBash = "bash"
+	ShellZsh  = "zsh"
+	ShellFish = "fish"
+)
+
+// WriteCompletion outputs a completion script for the named shell. The script
+// covers subcommand names at every level of the command tree and the flags of
+// each Delegator's Flags and each Command's Setup flag set. The completed
+// program name is the base name of the root flag set.
+//
+// Generating the script calls the Setup func of every Command in the tree, see
+// Command.Setup.
+func (r *Root) WriteCompletion(w io.Writer, shell string) (err error) {
+	var nodes []completionNode
+	err = Walk(r, func(path []string, d Directive, flags *flag.FlagSet) error {
+		nodes = append(nodes, newCompletionNode(path, d, flags))
+		return nil
+	})
+	if err != nil {
+		return
+	}
+
+	bw := bufio.NewWriter(w)
+	name := r.name()
+	switch shell {
+	case ShellBash:
+		writeBashCompletion(bw, name, nodes)
+	case ShellZsh:
+		writeZshCompletion(bw, name, nodes)
+	case ShellFish:
+		writeFishCompletion(bw, name, nodes)
+	default:
+		return fmt.Errorf("unsupported shell %q", shell)
+	}
+	return bw.Flush()
+}
+
+// completionNode is what a completion script needs to know about one point in
+// the command tree.
+type completionNode struct {
+	path  []string
+	subs  []completionItem
+	flags []completionItem
+}
+
+type completionItem struct {
+	name        string
+	aliases     []string
+	summary     string
+	takesValues bool
+}
+
+func newCompletionNode(path []string, d Directive, flags *flag.FlagSet) completionNode {
+	node := completionNode{path: path}
+	if del, ok := d.(*Delegator); ok {
+		for _, name := range sortedKeys(del.Subs) {
+			node.subs = append(node.subs, completionItem{
+				name:    name,
+				aliases: del.AliasesOf(name),
+				summary: firstLine(del.Subs[name].Summary()),
+			})
+		}
+	}
+	if flags != nil {
+		flags.VisitAll(func(f *flag.Flag) {
+			node.flags = append(node.flags, completionItem{
+				name:        f.Name,
+				summary:     firstLine(f.Usage),
+				takesValues: !isBoolFlag(f),
+			})
+		})
+	}
+	return node
+}
+
+func (n completionNode) key() string { return strings.Join(n.path, " ") }
+
+func firstLine(s string) string {
+	s, _, _ = strings.Cut(s, "\n")
+	return strings.TrimSpace(s)
+}
+
+// funcName makes a shell function name for the program and a suffix.
+func funcName(prog, suffix string) string {
+	id := strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, prog)
+	return "_" + id + suffix
+}
+
+// shQuote quotes s for a POSIX-like shell.
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes s for the fish shell.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// writeShellTables outputs functions shared by the bash and zsh scripts. They
+// let the script track which subcommand the user is in while skipping over
+// flags and their values.
+func writeShellTables(w io.Writer, prog string, nodes []completionNode) {
+	fmt.Fprintf(w, "%s() {\n\tcase \"$1|$2\" in\n", funcName(prog, "_next"))
+	for _, node := range nodes {
+		for _, sub := range node.subs {
+			next := strings.TrimSpace(node.key() + " " + sub.name)
+			patterns := []string{shQuote(node.key() + "|" + sub.name)}
+			for _, alias := range sub.aliases {
+				patterns = append(patterns, shQuote(node.key()+"|"+alias))
+			}
+			fmt.Fprintf(w, "\t%s) echo %s ;;\n", strings.Join(patterns, "|"), shQuote(next))
+		}
+	}
+	fmt.Fprint(w, "\tesac\n}\n\n")
+
+	fmt.Fprintf(w, "%s() {\n\tcase \"$1|$2\" in\n", funcName(prog, "_takes_value"))
+	for _, node := range nodes {
+		for _, f := range node.flags {
+			if !f.takesValues {
+				continue
+			}
+			fmt.Fprintf(w, "\t%s|%s) return 0 ;;\n",
+				shQuote(node.key()+"|-"+f.name), shQuote(node.key()+"|--"+f.name))
+		}
+	}
+	fmt.Fprint(w, "\tesac\n\treturn 1\n}\n\n")
+}
+
+func writeBashCompletion(w io.Writer, prog string, nodes []completionNode) {
+	fmt.Fprintf(w, "# bash completion for %s\n\n", prog)
+	writeShellTables(w, prog, nodes)
+
+	fmt.Fprintf(w, "%s() {\n\tcase \"$1\" in\n", funcName(prog, "_words"))
+	for _, node := range nodes {
+		names := make([]string, 0, len(node.subs))
+		for _, sub := range node.subs {
+			names = append(names, sub.name)
+		}
+		flags := make([]string, 0, len(node.flags))
+		for _, f := range node.flags {
+			flags = append(flags, "-"+f.name)
+		}
+		fmt.Fprintf(w, "\t%s) echo %s ;;\n", shQuote(node.key()+"|cmd"), shQuote(strings.Join(names, " ")))
+		fmt.Fprintf(w, "\t%s) echo %s ;;\n", shQuote(node.key()+"|flag"), shQuote(strings.Join(flags, " ")))
+	}
+	fmt.Fprint(w, "\tesac\n}\n\n")
+
+	fmt.Fprintf(w, `%[1]s() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" cmdpath="" word next i skip=0
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		word="${COMP_WORDS[i]}"
+		if ((skip)); then
+			skip=0
+			continue
+		fi
+		case "$word" in
+		-*=*) ;;
+		-*) %[2]s "$cmdpath" "$word" && skip=1 ;;
+		*)
+			next="$(%[3]s "$cmdpath" "$word")"
+			[[ -n "$next" ]] && cmdpath="$next"
+			;;
+		esac
+	done
+	if ((!skip)) && [[ "$cur" == -* ]]; then
+		COMPREPLY=($(compgen -W "$(%[4]s "$cmdpath|flag")" -- "$cur"))
+		return
+	fi
+	local cmds=""
+	((skip)) || cmds="$(%[4]s "$cmdpath|cmd")"
+	if [[ -n "$cmds" ]]; then
+		COMPREPLY=($(compgen -W "$cmds" -- "$cur"))
+		return
+	fi
+	# Ask the program for flag values and positional args.
+	local IFS=$'\n'
+	COMPREPLY=($(compgen -W "$("${COMP_WORDS[0]}" %[6]s "${COMP_WORDS[@]:1:COMP_CWORD-1}" "$cur" 2>/dev/null)" -- "$cur"))
+}
+
+complete -o default -F %[1]s %[5]s
+`,
+		funcName(prog, ""), funcName(prog, "_takes_value"), funcName(prog, "_next"),
+		funcName(prog, "_words"), prog, completeCmd)
+}
+
+func writeZshCompletion(w io.Writer, prog string, nodes []completionNode) {
+	fmt.Fprintf(w, "#compdef %s\n\n# zsh completion for %s\n\n", prog, prog)
+	writeShellTables(w, prog, nodes)
+
+	describe := func(name, summary string) string {
+		name = strings.NewReplacer(`\`, `\\`, ":", `\:`).Replace(name)
+		return shQuote(name + ":" + summary)
+	}
+	fmt.Fprintf(w, "%s() {\n\tcase \"$1\" in\n", funcName(prog, "_describe"))
+	for _, node := range nodes {
+		subs := make([]string, 0, len(node.subs))
+		for _, sub := range node.subs {
+			subs = append(subs, describe(sub.name, sub.summary))
+		}
+		flags := make([]string, 0, len(node.flags))
+		for _, f := range node.flags {
+			flags = append(flags, describe("-"+f.name, f.summary))
+		}
+		fmt.Fprintf(w, "\t%s) reply=(%s) ;;\n", shQuote(node.key()+"|cmd"), strings.Join(subs, " "))
+		fmt.Fprintf(w, "\t%s) reply=(%s) ;;\n", shQuote(node.key()+"|flag"), strings.Join(flags, " "))
+	}
+	fmt.Fprint(w, "\t*) reply=() ;;\n\tesac\n}\n\n")
+
+	fmt.Fprintf(w, `%[1]s() {
+	local cmdpath="" word next i skip=0
+	local -a reply
+	for ((i = 2; i < CURRENT; i++)); do
+		word="${words[i]}"
+		if ((skip)); then
+			skip=0
+			continue
+		fi
+		case "$word" in
+		-*=*) ;;
+		-*) %[2]s "$cmdpath" "$word" && skip=1 ;;
+		*)
+			next="$(%[3]s "$cmdpath" "$word")"
+			[[ -n "$next" ]] && cmdpath="$next"
+			;;
+		esac
+	done
+	if ((!skip)); then
+		if [[ "${words[CURRENT]}" == -* ]]; then
+			%[4]s "$cmdpath|flag"
+			_describe -t flags 'flags' reply
+			return
+		fi
+		%[4]s "$cmdpath|cmd"
+		if ((${#reply})); then
+			_describe -t commands 'commands' reply
+			return
+		fi
+	fi
+	# Ask the program for flag values and positional args.
+	local -a candidates
+	candidates=(${(f)"$(${words[1]} %[6]s "${(@)words[2,CURRENT-1]}" "${words[CURRENT]}" 2>/dev/null)"})
+	if ((${#candidates})); then
+		compadd -a candidates
+	else
+		_default
+	fi
+}
+
+compdef %[1]s %[5]s
+`,
+		funcName(prog, ""), funcName(prog, "_takes_value"), funcName(prog, "_next"),
+		funcName(prog, "_describe"), prog, completeCmd)
+}
+
+func writeFishCompletion(w io.Writer, prog string, nodes []completionNode) {
+	pathFunc, atFunc, dynamicFunc := funcName(prog, "_path"), funcName(prog, "_at"), funcName(prog, "_dynamic")
+	fmt.Fprintf(w, "# fish completion for %s\n\n", prog)
+
+	// The path function prints the current subcommand path. It fails when the
+	// word being completed is the value of a flag.
+	fmt.Fprintf(w, "function %s\n\tset -l cmdpath ''\n\tset -l skip 0\n", pathFunc)
+	fmt.Fprint(w, "\tfor word in (commandline -opc)[2..-1]\n")
+	fmt.Fprint(w, "\t\tif test $skip -eq 1\n\t\t\tset skip 0\n\t\t\tcontinue\n\t\tend\n")
+	fmt.Fprint(w, "\t\tswitch \"$cmdpath|$word\"\n")
+	for _, node := range nodes {
+		for _, f := range node.flags {
+			if f.takesValues {
+				fmt.Fprintf(w, "\t\tcase %s %s\n\t\t\tset skip 1\n",
+					fishQuote(node.key()+"|-"+f.name), fishQuote(node.key()+"|--"+f.name))
+			}
+		}
+		for _, sub := range node.subs {
+			next := strings.TrimSpace(node.key() + " " + sub.name)
+			patterns := []string{fishQuote(node.key() + "|" + sub.name)}
+			for _, alias := range sub.aliases {
+				patterns = append(patterns, fishQuote(node.key()+"|"+alias))
+			}
+			fmt.Fprintf(w, "\t\tcase %s\n\t\t\tset cmdpath %s\n", strings.Join(patterns, " "), fishQuote(next))
+		}
+	}
+	fmt.Fprint(w, "\t\tend\n\tend\n\ttest $skip -eq 0; or return 1\n\techo $cmdpath\nend\n\n")
+
+	fmt.Fprintf(w, "function %s\n\tset -l cmdpath (%s); or return 1\n\ttest \"$cmdpath\" = \"$argv[1]\"\nend\n\n", atFunc, pathFunc)
+
+	// The program is asked for candidates when completing a flag value or the
+	// positional args of a Command.
+	fmt.Fprintf(w, "function %s\n\tset -l cmdpath (%s); or return 0\n\tswitch \"$cmdpath\"\n", dynamicFunc, pathFunc)
+	var leaves []string
+	for _, node := range nodes {
+		if len(node.subs) < 1 {
+			leaves = append(leaves, fishQuote(node.key()))
+		}
+	}
+	if len(leaves) > 0 {
+		fmt.Fprintf(w, "\tcase %s\n\t\treturn 0\n", strings.Join(leaves, " "))
+	}
+	fmt.Fprint(w, "\tend\n\treturn 1\nend\n\n")
+	fmt.Fprintf(w, "complete -c %s -n %s -a %s\n", prog, dynamicFunc,
+		fishQuote("("+prog+" "+completeCmd+" (commandline -opc)[2..-1] (commandline -ct))"))
+
+	for _, node := range nodes {
+		cond := fishQuote(atFunc + " " + fishQuote(node.key()))
+		for _, sub := range node.subs {
+			fmt.Fprintf(w, "complete -c %s -f -n %s -a %s -d %s\n", prog, cond, fishQuote(sub.name), fishQuote(sub.summary))
+		}
+		for _, f := range node.flags {
+			var requires string
+			if f.takesValues {
+				requires = " -r"
+			}
+			fmt.Fprintf(w, "complete -c %s -n %s -o %s%s -d %s\n", prog, cond, fishQuote(f.name), requires, fishQuote(f.summary))
+		}
+	}
+}
+
+// completeCmd is the hidden first arg to Root.Run that requests completions.
+const completeCmd = "__complete"
+
+// A Completer suggests values during shell completion. Implement it on a
+// flag.Value to complete the values of a flag. The args are the positional
+// arguments found so far, toComplete is what the user has typed so far.
+type Completer interface {
+	Complete(ctx context.Context, args []string, toComplete string) []string
+}
+
+// complete resolves the partial command line in args the same way as Perform
+// would, and then outputs candidates for the last arg.
+func (r *Root) complete(ctx context.Context, w io.Writer, args []string) error {
+	var toComplete string
+	if len(args) > 0 {
+		args, toComplete = args[:len(args)-1], args[len(args)-1]
+	}
+	bw := bufio.NewWriter(w)
+	for _, candidate := range completeDirective(ctx, r.Delegator, nil, &frame{root: r}, args, toComplete) {
+		fmt.Fprintln(bw, candidate)
+	}
+	return bw.Flush()
+}
+
+// completeDirective finds the candidates for toComplete, after resolving args
+// the way Delegator.Perform would. The frame carries the settings inherited
+// from ancestors.
+func completeDirective(ctx context.Context, d Directive, parentFlags *flag.FlagSet, fr *frame, args []string, toComplete string) []string {
+	var flags *flag.FlagSet
+	interspersed := false
+	switch node := d.(type) {
+	case *Delegator:
+		fr.persistent = appendPersistent(fr.persistent, node)
+		flags = node.Flags
+	case *Command:
+		flags = node.inspectFlags(parentFlags)
+		interspersed = fr.interspersed || node.Interspersed
+	default:
+		return nil
+	}
+	if flags == nil {
+		flags = flag.NewFlagSet("", flag.ContinueOnError)
+	}
+
+	// Parse a throwaway copy so that errors don't print anything or exit.
+	quiet := cloneFlagSet(flags)
+	quiet.Init(flags.Name(), flag.ContinueOnError)
+	quiet.SetOutput(io.Discard)
+	quiet.Usage = func() {}
+	definePersistent(quiet, fr.persistent)
+	args = expandShortFlags(quiet, args, interspersed)
+	var err error
+	if interspersed {
+		err = parseInterspersed(quiet, args)
+	} else {
+		err = quiet.Parse(args)
+	}
+	if err != nil {
+		// Maybe the last arg is a flag and toComplete is its value.
+		if len(args) < 1 {
+			return nil
+		}
+		f := quiet.Lookup(strings.TrimLeft(args[len(args)-1], "-"))
+		if f == nil || isBoolFlag(f) {
+			return nil
+		}
+		return completeFlagValue(ctx, f, nil, toComplete)
+	}
+	positionals := quiet.Args()
+
+	if del, ok := d.(*Delegator); ok && len(positionals) > 0 {
+		prefixMatching := fr.prefixMatching || del.PrefixMatching
+		name, sub, err := del.selectSub(positionals[0], prefixMatching)
+		if err != nil {
+			return nil
+		}
+		child := fr.child(name)
+		child.prefixMatching = prefixMatching
+		child.interspersed = fr.interspersed || del.Interspersed
+		return completeDirective(ctx, sub, flags, child, positionals[1:], toComplete)
+	}
+
+	if strings.HasPrefix(toComplete, "-") {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(toComplete, "-"), "=")
+		if hasValue {
+			f := quiet.Lookup(name)
+			if f == nil {
+				return nil
+			}
+			prefix := toComplete[:len(toComplete)-len(value)]
+			out := completeFlagValue(ctx, f, positionals, value)
+			for i, candidate := range out {
+				out[i] = prefix + candidate
+			}
+			return out
+		}
+
+		var out []string
+		quiet.VisitAll(func(f *flag.Flag) {
+			if strings.HasPrefix(f.Name, name) {
+				out = append(out, "-"+f.Name)
+			}
+		})
+		return out
+	}
+
+	switch node := d.(type) {
+	case *Delegator:
+		var out []string
+		for _, name := range sortedKeys(node.Subs) {
+			if strings.HasPrefix(name, toComplete) {
+				out = append(out, name)
+			}
+		}
+		return out
+	case *Command:
+		if node.Complete != nil {
+			return node.Complete(ctx, positionals, toComplete)
+		}
+	}
+	return nil
+}
+
+func completeFlagValue(ctx context.Context, f *flag.Flag, args []string, toComplete string) []string {
+	if c, ok := f.Value.(Completer); ok {
+		return c.Complete(ctx, args, toComplete)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package alf_test
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+func TestRootWriteCompletion(t *testing.T) {
+	for _, shell := range []string{alf.ShellBash, alf.ShellZsh, alf.ShellFish} {
+		t.Run(shell, func(t *testing.T) {
+			var usage string
+			root := newStubRoot("stub", &usage, nil)
+
+			var buf bytes.Buffer
+			if err := root.WriteCompletion(&buf, shell); err != nil {
+				t.Fatal(err)
+			}
+			out := buf.String()
+			for _, mention := range []string{"stub", "alpha", "delta india", "foo", "quebec", "qux"} {
+				if !strings.Contains(out, mention) {
+					t.Errorf("output does not mention %q", mention)
+				}
+			}
+			if usage != "" {
+				t.Errorf("should not call any Usage func, called %q", usage)
+			}
+		})
+	}
+
+	t.Run("unsupported shell", func(t *testing.T) {
+		root := newStubRoot("stub", nil, nil)
+		if err := root.WriteCompletion(&bytes.Buffer{}, "cmd.exe"); err == nil {
+			t.Error("expected error, got none")
+		}
+	})
+
+	t.Run("bash", func(t *testing.T) {
+		bash, err := exec.LookPath("bash")
+		if err != nil {
+			t.Skip("bash not available")
+		}
+		root := newStubRoot("stub", nil, nil)
+		script := filepath.Join(t.TempDir(), "stub.bash")
+		var buf bytes.Buffer
+		if err = root.WriteCompletion(&buf, alf.ShellBash); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(script, buf.Bytes(), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			words []string
+			exp   string
+		}{
+			{words: []string{"stub", ""}, exp: "alpha bravo charlie delta"},
+			{words: []string{"stub", "-foo", "f", "d"}, exp: "delta"},
+			{words: []string{"stub", "delta", ""}, exp: "echo foxtrot golf hotel india"},
+			{words: []string{"stub", "delta", "-bar", "1", "india", ""}, exp: "bar foo"},
+			{words: []string{"stub", "delta", "foxtrot", "-"}, exp: "-qux"},
+			{words: []string{"stub", "delta", "-bar", ""}, exp: ""},
+		}
+		for _, test := range tests {
+			cmd := exec.Command(bash, "--norc", "-c", `source "$1"; shift; COMP_WORDS=("$@"); COMP_CWORD=$(($# - 1)); _stub; echo "${COMPREPLY[*]}"`, "-", script)
+			cmd.Args = append(cmd.Args, test.words...)
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(string(out)); got != test.exp {
+				t.Errorf("words %q; got %q, expected %q", test.words, got, test.exp)
+			}
+		}
+	})
+}
+
+type stubCompleter []string
+
+func (c *stubCompleter) String() string     { return strings.Join(*c, ",") }
+func (c *stubCompleter) Set(v string) error { *c = append(*c, v); return nil }
+func (c *stubCompleter) Complete(ctx context.Context, args []string, toComplete string) []string {
+	return []string{"prod", "staging"}
+}
+
+func TestRootComplete(t *testing.T) {
+	newRoot := func() alf.Root {
+		root := newStubRoot("stub", nil, nil)
+		var envs stubCompleter
+		root.Flags.Var(&envs, "env", "environment")
+		root.Subs["kilo"] = &alf.Command{
+			Description: "has completions",
+			Setup: func(p flag.FlagSet) *flag.FlagSet {
+				f := newMutedFlagSet("kilo", flag.ContinueOnError)
+				f.Bool("lima", false, "lll")
+				return f
+			},
+			Complete: func(ctx context.Context, args []string, toComplete string) []string {
+				return []string{strings.Join(args, "+") + "|" + toComplete}
+			},
+			Run: func(ctx context.Context) error { return nil },
+		}
+		root.Subs["mike"] = &alf.Command{
+			Description: "has interspersed flags",
+			Setup: func(p flag.FlagSet) *flag.FlagSet {
+				f := newMutedFlagSet("mike", flag.ContinueOnError)
+				f.Bool("bravo", false, "bbb")
+				f.Var(new(stubCompleter), "charlie", "ccc")
+				alf.ShortFlag(f, "b", "bravo")
+				alf.ShortFlag(f, "c", "charlie")
+				return f
+			},
+			Complete: func(ctx context.Context, args []string, toComplete string) []string {
+				return []string{strings.Join(args, "+") + "|" + toComplete}
+			},
+			Run:          func(ctx context.Context) error { return nil },
+			Interspersed: true,
+		}
+		root.Aliases = map[string]string{"k": "kilo"}
+		return root
+	}
+
+	tests := []struct {
+		args []string
+		exp  []string
+	}{
+		{args: []string{}, exp: []string{"alpha", "bravo", "charlie", "delta", "kilo", "mike"}},
+		{args: []string{""}, exp: []string{"alpha", "bravo", "charlie", "delta", "kilo", "mike"}},
+		{args: []string{"d"}, exp: []string{"delta"}},
+		{args: []string{"-f"}, exp: []string{"-foo"}},
+		{args: []string{"-foo", "x", "delta", "i"}, exp: []string{"india"}},
+		{args: []string{"delta", "india", ""}, exp: []string{"bar", "foo"}},
+		{args: []string{"delta", "foxtrot", "-"}, exp: []string{"-qux"}},
+		{args: []string{"delta", "zulu", ""}, exp: nil},
+		{args: []string{"-env", ""}, exp: []string{"prod", "staging"}},
+		{args: []string{"-env=p"}, exp: []string{"-env=prod", "-env=staging"}},
+		{args: []string{"kilo", "-lima", "x", "y", "z"}, exp: []string{"x+y|z"}},
+		{args: []string{"mike", "paris", "-charlie", ""}, exp: []string{"prod", "staging"}},
+		{args: []string{"mike", "paris", "-bravo", "rome", ""}, exp: []string{"paris+rome|"}},
+		{args: []string{"mike", "-bc", ""}, exp: []string{"prod", "staging"}},
+		{args: []string{"mike", "-bcx", "paris", ""}, exp: []string{"paris|"}},
+		{args: []string{"kilo", ""}, exp: []string{"|"}},
+		{args: []string{"k", "a", ""}, exp: []string{"a|"}},
+	}
+
+	for _, test := range tests {
+		root := newRoot()
+		var err error
+		out := captureStdout(t, func() {
+			err = root.Run(context.Background(), append([]string{"__complete"}, test.args...))
+		})
+		if err != nil {
+			t.Errorf("args %q; unexpected error %v", test.args, err)
+		}
+		got := strings.Fields(out)
+		if strings.Join(got, " ") != strings.Join(test.exp, " ") {
+			t.Errorf("args %q; got %q, expected %q", test.args, got, test.exp)
+		}
+	}
+}
+
+// captureStdout collects everything written to os.Stdout while fn runs.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stdout, fn)
+}
+
+// captureFile collects what fn writes to *file, such as os.Stdout.
+func captureFile(t *testing.T, file **os.File, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := *file
+	*file = w
+	defer func() { *file = orig }()
+
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
@@ -0,0 +1,303 @@
+package alf
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Config holds flag values to apply to the command tree. It's keyed by section,
+// then by flag name. A section is a command path, the subcommand names joined
+// by dots, such as "bar.cities". The empty section is for the Root flags. A
+// flag with more than one value is set once per value, in order.
+//
+// A value from a Config is only applied to a flag that wasn't set from the
+// command line or from an environment variable. See Root.LoadConfig.
+type Config map[string]map[string][]string
+
+// ReadConfigFile reads and parses a configuration file. A file with the
+// extension ".json" is parsed with ParseJSONConfig, anything else is parsed
+// with ParseINIConfig.
+func ReadConfigFile(filename string) (Config, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	parse := ParseINIConfig
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		parse = ParseJSONConfig
+	}
+	out, err := parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return out, nil
+}
+
+// ParseJSONConfig reads a Config from a JSON object. Members with a string,
+// number or boolean value set the flag of the same name. An array sets the
+// flag once per item. Members with an object value are sections for the
+// subcommand of the same name, nested as deep as the command tree.
+//
+//	{"verbose": true, "bar": {"alpha": 2, "cities": {"charlie": "chan"}}}
+func ParseJSONConfig(r io.Reader) (Config, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	out := make(Config)
+	if err := out.addJSON(nil, obj); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c Config) addJSON(path []string, obj map[string]any) error {
+	section := strings.Join(path, ".")
+	for key, val := range obj {
+		switch v := val.(type) {
+		case map[string]any:
+			if err := c.addJSON(append(path[:len(path):len(path)], key), v); err != nil {
+				return err
+			}
+		case []any:
+			for _, item := range v {
+				str, err := jsonScalar(item)
+				if err != nil {
+					return fmt.Errorf("%s: %w", configKey(section, key), err)
+				}
+				c.add(section, key, str)
+			}
+		default:
+			str, err := jsonScalar(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", configKey(section, key), err)
+			}
+			c.add(section, key, str)
+		}
+	}
+	return nil
+}
+
+func jsonScalar(val any) (string, error) {
+	switch v := val.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", fmt.Errorf("unsupported value %v, expected a string, number or boolean", val)
+}
+
+// ParseINIConfig reads a Config from a simple INI format. Each line is a
+// section header, a key and value separated by "=", a comment or blank. Keys
+// before the first section header are for the Root flags. A value may be
+// double-quoted, to keep leading or trailing spaces. Repeating a key sets the
+// flag once per value.
+//
+//	# comments start with "#" or ";"
+//	verbose = true
+//
+//	[bar]
+//	alpha = 2
+//
+//	[bar.cities]
+//	charlie = "chan"
+func ParseINIConfig(r io.Reader) (Config, error) {
+	out := make(Config)
+	scanner := bufio.NewScanner(r)
+	var section string
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "", strings.HasPrefix(line, "#"), strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: expected a section header like [name]", lineNum)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			out.addSection(section)
+			continue
+		}
+
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNum)
+		}
+		key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+		if key == "" {
+			return nil, fmt.Errorf("line %d: missing key", lineNum)
+		}
+		if strings.HasPrefix(val, `"`) {
+			unquoted, err := strconv.Unquote(val)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid quoted value %s", lineNum, val)
+			}
+			val = unquoted
+		}
+		out.add(section, key, val)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c Config) addSection(section string) {
+	if _, ok := c[section]; !ok {
+		c[section] = make(map[string][]string)
+	}
+}
+
+func (c Config) add(section, key, val string) {
+	c.addSection(section)
+	c[section][key] = append(c[section][key], val)
+}
+
+// configKey describes where a flag is in a Config, for error messages.
+func configKey(section, key string) string {
+	if section == "" {
+		return fmt.Sprintf("%q", key)
+	}
+	return fmt.Sprintf("%q in section [%s]", key, section)
+}
+
+// validateConfig checks that every section of the Config names a command path
+// in the tree and that every key names a flag defined at that level. A flag
+// that a Command inherits from its parent must be set in the parent's section.
+// A flag that only has the same name as an ancestor's flag is not inherited,
+// see (*frame).inherited.
+func validateConfig(r *Root, cfg Config) error {
+	if len(cfg) < 1 {
+		return nil
+	}
+	// levels has the flags of each level, keyed by section name. They're noted
+	// during the Walk, because a flag set made by copying its parent shares
+	// its parent's map of flags.
+	levels := make(map[string]configLevel)
+	err := Walk(r, func(path []string, d Directive, flags *flag.FlagSet) error {
+		level := configLevel{all: make(map[string]*flag.Flag), own: make(map[string]bool)}
+		if flags != nil {
+			flags.VisitAll(func(f *flag.Flag) {
+				level.all[f.Name] = f
+				if configOwner(levels, path, f) < 0 {
+					level.own[f.Name] = true
+				}
+			})
+		}
+		levels[strings.Join(path, ".")] = level
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sections := make([]string, 0, len(cfg))
+	for section := range cfg {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+	for _, section := range sections {
+		level, ok := levels[section]
+		if !ok {
+			return fmt.Errorf("config: unknown section [%s], it's not a command path", section)
+		}
+		keys := make([]string, 0, len(cfg[section]))
+		for key := range cfg[section] {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if level.own[key] {
+				continue
+			}
+			var path []string
+			if section != "" {
+				path = strings.Split(section, ".")
+			}
+			if f, ok := level.all[key]; ok {
+				if depth := configOwner(levels, path, f); depth >= 0 {
+					return fmt.Errorf("config: flag %s is inherited, set it in %s instead",
+						configKey(section, key), configSectionName(path[:depth]))
+				}
+			}
+			return fmt.Errorf("config: unknown flag %s", configKey(section, key))
+		}
+	}
+	return nil
+}
+
+// configLevel has the flags at one level of the command tree.
+type configLevel struct {
+	// all has every flag at the level, keyed by name.
+	all map[string]*flag.Flag
+	// own has the names of the flags defined at the level, rather than
+	// inherited.
+	own map[string]bool
+}
+
+// configOwner finds which ancestor of path defines the flag. It returns the
+// length of the ancestor's path, or -1 if no ancestor defines the flag.
+func configOwner(levels map[string]configLevel, path []string, f *flag.Flag) int {
+	for depth := len(path) - 1; depth >= 0; depth-- {
+		level := levels[strings.Join(path[:depth], ".")]
+		if a, ok := level.all[f.Name]; ok && level.own[f.Name] && sameValue(a.Value, f.Value) {
+			return depth
+		}
+	}
+	return -1
+}
+
+func configSectionName(path []string) string {
+	if len(path) < 1 {
+		return "the top level"
+	}
+	return "section [" + strings.Join(path, ".") + "]"
+}
+
+// applyConfig sets any flag at this frame's level that wasn't already set from
+// the command line or the environment with its value from the Config.
+// Inherited flags are left out, they're applied at the level where they're
+// defined. Persistent flags are left to applyPersistent.
+func applyConfig(fr *frame, flags *flag.FlagSet) error {
+	section := strings.Join(fr.path, ".")
+	values := fr.config[section]
+	if len(values) < 1 {
+		return nil
+	}
+	set := setFlags(flags)
+
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if err != nil || set[f.Name] || fr.inherited(f) || fr.isPersistent(f) {
+			return
+		}
+		err = setFromConfig(flags, section, f.Name, values[f.Name])
+	})
+	return err
+}
+
+// setFromConfig sets the flag with each of its values from a section of the
+// Config.
+func setFromConfig(flags *flag.FlagSet, section, name string, values []string) error {
+	for _, val := range values {
+		if err := flags.Set(name, val); err != nil {
+			return fmt.Errorf("config: invalid value %q for %s: %w", val, configKey(section, name), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package alf_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+func TestParseConfig(t *testing.T) {
+	expected := alf.Config{
+		"":           {"foo": {"fred"}},
+		"bar":        {"alpha": {"2"}},
+		"bar.cities": {"charlie": {" chan ", "chuck"}, "delta": {"true"}},
+	}
+
+	t.Run("ini", func(t *testing.T) {
+		got, err := alf.ParseINIConfig(strings.NewReader(`
+# comment
+foo = fred
+
+; another comment
+[bar]
+alpha=2
+
+[ bar.cities ]
+charlie = " chan "
+charlie = chuck
+delta = true
+`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("wrong config\ngot      %v\nexpected %v", got, expected)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		got, err := alf.ParseJSONConfig(strings.NewReader(`{
+	"foo": "fred",
+	"bar": {
+		"alpha": 2,
+		"cities": {"charlie": [" chan ", "chuck"], "delta": true}
+	}
+}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("wrong config\ngot      %v\nexpected %v", got, expected)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, test := range []struct {
+			name   string
+			parse  func(r *strings.Reader) (alf.Config, error)
+			input  string
+			expErr string
+		}{
+			{"ini header", iniParser, "[bar", "line 1: expected a section header"},
+			{"ini no value", iniParser, "\nfoo", "line 2: expected key = value"},
+			{"ini no key", iniParser, "= fred", "line 1: missing key"},
+			{"ini bad quotes", iniParser, `foo = "fred`, "line 1: invalid quoted value"},
+			{"json syntax", jsonParser, `{"foo": `, "unexpected EOF"},
+			{"json null", jsonParser, `{"bar": {"alpha": null}}`, `"alpha" in section [bar]: unsupported value`},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				_, err := test.parse(strings.NewReader(test.input))
+				if err == nil || !strings.Contains(err.Error(), test.expErr) {
+					t.Errorf("expected error with %q, got %v", test.expErr, err)
+				}
+			})
+		}
+	})
+}
+
+func iniParser(r *strings.Reader) (alf.Config, error)  { return alf.ParseINIConfig(r) }
+func jsonParser(r *strings.Reader) (alf.Config, error) { return alf.ParseJSONConfig(r) }
+
+func TestReadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"conf.json": `{"bar": {"alpha": 2}}`,
+		"conf.ini":  "[bar]\nalpha = 2\n",
+	}
+	for name, contents := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(contents), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := alf.ReadConfigFile(filename)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if exp := (alf.Config{"bar": {"alpha": {"2"}}}); !reflect.DeepEqual(got, exp) {
+			t.Errorf("%s: got %v, expected %v", name, got, exp)
+		}
+	}
+
+	if _, err := alf.ReadConfigFile(filepath.Join(dir, "missing.ini")); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+}
+
+func TestRootLoadConfig(t *testing.T) {
+	config := alf.Config{
+		"":           {"foo": {"fred"}},
+		"bar":        {"alpha": {"2"}},
+		"bar.cities": {"charlie": {"chan"}},
+	}
+
+	tests := []struct {
+		name   string
+		config alf.Config
+		env    map[string]string
+		args   []string
+		exp    envTestArgs
+		expErr string
+	}{
+		{
+			name:   "from config",
+			config: config,
+			args:   []string{"bar", "cities"},
+			exp:    envTestArgs{foo: "fred", alpha: 2, charlie: "chan", ownFoo: "otto"},
+		},
+		{
+			name:   "env takes precedence",
+			config: config,
+			env:    map[string]string{"STUB_FOO": "felix", "STUB_BAR_CITIES_CHARLIE": "chuck"},
+			args:   []string{"bar", "cities"},
+			exp:    envTestArgs{foo: "felix", alpha: 2, charlie: "chuck", ownFoo: "otto"},
+		},
+		{
+			name:   "command line takes precedence",
+			config: config,
+			env:    map[string]string{"STUB_BAR_ALPHA": "3"},
+			args:   []string{"bar", "-alpha", "4", "cities"},
+			exp:    envTestArgs{foo: "fred", alpha: 4, charlie: "chan", ownFoo: "otto"},
+		},
+		{
+			name:   "short form on the command line takes precedence",
+			config: config,
+			args:   []string{"bar", "cities", "-c", "chuck"},
+			exp:    envTestArgs{foo: "fred", alpha: 2, charlie: "chuck", ownFoo: "otto"},
+		},
+		{
+			// Nothing to check, so the Setup func of "own" isn't called.
+			name: "no config",
+			args: []string{"bar", "cities"},
+			exp:  envTestArgs{foo: "frank", alpha: 1, charlie: "parker"},
+		},
+		{
+			name:   "unknown section",
+			config: alf.Config{"bar.citys": {"charlie": {"chan"}}},
+			args:   []string{"bar", "cities"},
+			exp:    envTestArgs{foo: "frank", alpha: 1, charlie: "parker", ownFoo: "otto"},
+			expErr: "config: unknown section [bar.citys]",
+		},
+		{
+			name:   "unknown flag",
+			config: alf.Config{"bar": {"alfa": {"2"}}},
+			args:   []string{"bar", "cities"},
+			exp:    envTestArgs{foo: "frank", alpha: 1, charlie: "parker", ownFoo: "otto"},
+			expErr: `config: unknown flag "alfa" in section [bar]`,
+		},
+		{
+			name:   "inherited flag",
+			config: alf.Config{"bar.cities": {"alpha": {"2"}}},
+			args:   []string{"bar", "cities"},
+			exp:    envTestArgs{foo: "frank", alpha: 1, charlie: "parker", ownFoo: "otto"},
+			expErr: `config: flag "alpha" in section [bar.cities] is inherited, set it in section [bar] instead`,
+		},
+		{
+			name:   "same name as an ancestor flag",
+			config: alf.Config{"": {"foo": {"fred"}}, "own": {"foo": {"oscar"}}},
+			args:   []string{"own"},
+			exp:    envTestArgs{foo: "fred", alpha: 1, charlie: "parker", ownFoo: "oscar"},
+		},
+		{
+			name:   "invalid value",
+			config: alf.Config{"bar": {"alpha": {"two"}}},
+			args:   []string{"bar", "cities"},
+			exp:    envTestArgs{foo: "frank", charlie: "parker", ownFoo: "otto"},
+			expErr: `config: invalid value "two" for "alpha" in section [bar]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for key, val := range test.env {
+				t.Setenv(key, val)
+			}
+			var out bytes.Buffer
+			var got envTestArgs
+			root := newEnvTestRoot("STUB", &out, &got)
+			root.LoadConfig = func(ctx context.Context) (alf.Config, error) { return test.config, nil }
+			err := root.Run(context.Background(), test.args)
+			if test.expErr == "" && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			} else if test.expErr != "" && (err == nil || !strings.Contains(err.Error(), test.expErr)) {
+				t.Fatalf("expected error with %q, got %v", test.expErr, err)
+			}
+			if got != test.exp {
+				t.Errorf("got %+v, expected %+v", got, test.exp)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package alf
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// A Constraint is a rule about which flags of a Command are set. See
+// Command.Constraints. A flag counts as set if it's specified on the command
+// line, or comes from an environment variable or a Config, but not if it just
+// has its default value.
+type Constraint struct {
+	kind  constraintKind
+	names []string
+}
+
+type constraintKind int
+
+const (
+	constraintRequired constraintKind = iota
+	constraintMutuallyExclusive
+	constraintOneRequired
+	constraintRequires
+)
+
+// Required makes each of the flags mandatory.
+func Required(names ...string) Constraint {
+	return Constraint{kind: constraintRequired, names: names}
+}
+
+// MutuallyExclusive allows at most one of the flags to be set.
+func MutuallyExclusive(names ...string) Constraint {
+	return Constraint{kind: constraintMutuallyExclusive, names: names}
+}
+
+// OneRequired makes at least one of the flags mandatory.
+func OneRequired(names ...string) Constraint {
+	return Constraint{kind: constraintOneRequired, names: names}
+}
+
+// Requires makes the other flags mandatory when the named flag is set. For
+// example, Requires("tls-cert", "tls-key") means that -tls-cert can't be used
+// without -tls-key.
+func Requires(name string, others ...string) Constraint {
+	return Constraint{kind: constraintRequires, names: append([]string{name}, others...)}
+}
+
+// String describes the rule, for help messages.
+func (c Constraint) String() string {
+	switch c.kind {
+	case constraintRequired:
+		return joinFlagNames(c.names) + " " + pluralize(len(c.names), "is", "are") + " required"
+	case constraintMutuallyExclusive:
+		return "only one of " + joinFlagNames(c.names) + " may be set"
+	case constraintOneRequired:
+		return "at least one of " + joinFlagNames(c.names) + " is required"
+	case constraintRequires:
+		return joinFlagNames(c.names[:1]) + " requires " + joinFlagNames(c.names[1:])
+	}
+	return ""
+}
+
+// violations lists how the constraint is broken, if at all. The set map has
+// the names of the flags that are set.
+func (c Constraint) violations(set map[string]bool) (out []string) {
+	switch c.kind {
+	case constraintRequired:
+		for _, name := range c.names {
+			if !set[name] {
+				out = append(out, "-"+name+" is required")
+			}
+		}
+	case constraintMutuallyExclusive:
+		if given := filterNames(c.names, set, true); len(given) > 1 {
+			out = append(out, joinFlagNames(given)+" can't be used together")
+		}
+	case constraintOneRequired:
+		if given := filterNames(c.names, set, true); len(given) < 1 {
+			out = append(out, c.String())
+		}
+	case constraintRequires:
+		if missing := filterNames(c.names[1:], set, false); set[c.names[0]] && len(missing) > 0 {
+			out = append(out, joinFlagNames(c.names[:1])+" requires "+joinFlagNames(missing))
+		}
+	}
+	return
+}
+
+// ConstraintError is returned when a Command's flags break its Constraints. It
+// wraps ErrShowUsage, so the Command's usage is shown.
+type ConstraintError struct {
+	// Violations describes each broken rule.
+	Violations []string
+}
+
+func (e *ConstraintError) Error() string { return strings.Join(e.Violations, "; ") }
+
+func (e *ConstraintError) Unwrap() error { return ErrShowUsage }
+
+// constraints has the Command's Constraints, along with the flags made
+// mandatory by a required tag, see Bind.
+func (c *Command) constraints(fr *frame, flags *flag.FlagSet) []Constraint {
+	out := c.Constraints
+	if required := fr.requiredFlags(flags, true); len(required) > 0 {
+		out = append(out[:len(out):len(out)], required...)
+	}
+	return out
+}
+
+// checkConstraints checks every constraint and reports all of the violations
+// together. A flag counts as set if it was set at any level along the path, see
+// setAlongPath.
+func (fr *frame) checkConstraints(constraints []Constraint, flags *flag.FlagSet) error {
+	if len(constraints) < 1 {
+		return nil
+	}
+	set := fr.setAlongPath(flags)
+
+	var violations []string
+	for _, c := range constraints {
+		for _, name := range c.names {
+			if flags.Lookup(name) == nil {
+				return fmt.Errorf("constraint %q refers to undefined flag %q", c, name)
+			}
+		}
+		violations = append(violations, c.violations(set)...)
+	}
+	if len(violations) > 0 {
+		return &ConstraintError{Violations: violations}
+	}
+	return nil
+}
+
+func filterNames(names []string, set map[string]bool, want bool) (out []string) {
+	for _, name := range names {
+		if set[name] == want {
+			out = append(out, name)
+		}
+	}
+	return
+}
+
+func joinFlagNames(names []string) string {
+	dashed := make([]string, len(names))
+	for i, name := range names {
+		dashed[i] = "-" + name
+	}
+	return strings.Join(dashed, ", ")
+}
+
+func pluralize(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,186 @@
+package alf_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+func TestConstraints(t *testing.T) {
+	newRoot := func(out *bytes.Buffer, ran *bool, constraints ...alf.Constraint) *alf.Root {
+		root := &alf.Root{
+			Delegator: &alf.Delegator{
+				Description:     "root",
+				Flags:           newMutedFlagSet("root", flag.ContinueOnError),
+				PersistentFlags: newMutedFlagSet("root", flag.ContinueOnError),
+				Subs: map[string]alf.Directive{
+					"serve": &alf.Command{
+						Description: "has constraints",
+						Setup: func(p flag.FlagSet) *flag.FlagSet {
+							f := flag.NewFlagSet("serve", flag.ContinueOnError)
+							f.SetOutput(out)
+							f.String("name", "", "nnn")
+							f.Bool("json", false, "jjj")
+							f.Bool("yaml", false, "yyy")
+							f.Bool("alfa", false, "aaa")
+							f.Bool("bravo", false, "bbb")
+							f.String("tls-cert", "", "ccc")
+							f.String("tls-key", "", "kkk")
+							alf.ShortFlag(f, "n", "name")
+							return f
+						},
+						Run: func(ctx context.Context) error {
+							*ran = true
+							return nil
+						},
+						Constraints: constraints,
+					},
+				},
+			},
+			EnvPrefix: "STUB",
+		}
+		root.PersistentFlags.String("profile", "", "ppp")
+		return root
+	}
+	constraints := []alf.Constraint{
+		alf.Required("name"),
+		alf.MutuallyExclusive("json", "yaml"),
+		alf.OneRequired("alfa", "bravo"),
+		alf.Requires("tls-cert", "tls-key"),
+	}
+
+	tests := []struct {
+		name          string
+		args          []string
+		env           map[string]string
+		expViolations []string
+	}{
+		{
+			name: "ok",
+			args: []string{"-name", "x", "-json", "-alfa", "-tls-cert", "c", "-tls-key", "k"},
+		},
+		{
+			name: "short form counts",
+			args: []string{"-n", "x", "-bravo"},
+		},
+		{
+			name: "env counts",
+			args: []string{"-alfa"},
+			env:  map[string]string{"STUB_SERVE_NAME": "x"},
+		},
+		{
+			name: "all violations together",
+			args: []string{"-json", "-yaml", "-tls-cert", "c"},
+			expViolations: []string{
+				"-name is required",
+				"-json, -yaml can't be used together",
+				"at least one of -alfa, -bravo is required",
+				"-tls-cert requires -tls-key",
+			},
+		},
+		{
+			name:          "explicitly empty counts",
+			args:          []string{"-name", "", "-alfa"},
+			expViolations: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for key, val := range test.env {
+				t.Setenv(key, val)
+			}
+			var out bytes.Buffer
+			var ran bool
+			err := newRoot(&out, &ran, constraints...).Run(context.Background(), append([]string{"serve"}, test.args...))
+
+			var cerr *alf.ConstraintError
+			if test.expViolations == nil {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				if !ran {
+					t.Error("command should run")
+				}
+				return
+			}
+			if !errors.As(err, &cerr) {
+				t.Fatalf("expected a %T, got %v", cerr, err)
+			}
+			if !reflect.DeepEqual(cerr.Violations, test.expViolations) {
+				t.Errorf("wrong violations\ngot      %q\nexpected %q", cerr.Violations, test.expViolations)
+			}
+			if !errors.Is(err, alf.ErrShowUsage) {
+				t.Error("should wrap ErrShowUsage")
+			}
+			if !strings.Contains(out.String(), "Usage:") {
+				t.Error("should show usage")
+			}
+			if ran {
+				t.Error("command should not run")
+			}
+		})
+	}
+
+	t.Run("undefined flag", func(t *testing.T) {
+		var ran bool
+		err := newRoot(&bytes.Buffer{}, &ran, alf.Required("nope")).Run(context.Background(), []string{"serve"})
+		if err == nil || !strings.Contains(err.Error(), `undefined flag "nope"`) {
+			t.Errorf("expected error about undefined flag, got %v", err)
+		}
+	})
+
+	t.Run("persistent flag", func(t *testing.T) {
+		for _, test := range []struct {
+			name string
+			args []string
+			env  map[string]string
+		}{
+			{name: "after the subcommand", args: []string{"serve", "-profile", "x"}},
+			{name: "before the subcommand", args: []string{"-profile", "x", "serve"}},
+			{name: "from env", args: []string{"serve"}, env: map[string]string{"STUB_PROFILE": "x"}},
+		} {
+			t.Run(test.name, func(t *testing.T) {
+				for key, val := range test.env {
+					t.Setenv(key, val)
+				}
+				var ran bool
+				err := newRoot(&bytes.Buffer{}, &ran, alf.Required("profile")).Run(context.Background(), test.args)
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				if !ran {
+					t.Error("command should run")
+				}
+			})
+		}
+		var ran bool
+		err := newRoot(&bytes.Buffer{}, &ran, alf.Required("profile")).Run(context.Background(), []string{"serve"})
+		if err == nil || err.Error() != "-profile is required" {
+			t.Errorf("expected -profile to be required, got %v", err)
+		}
+	})
+
+	t.Run("help", func(t *testing.T) {
+		var out bytes.Buffer
+		var ran bool
+		newRoot(&out, &ran, append(constraints, alf.Required("json", "yaml"))...).Run(context.Background(), []string{"serve", "-h"})
+		exp := `Constraints:
+
+	-name is required
+	only one of -json, -yaml may be set
+	at least one of -alfa, -bravo is required
+	-tls-cert requires -tls-key
+	-json, -yaml are required
+`
+		if !strings.Contains(out.String(), exp) {
+			t.Errorf("help does not contain\n%s\ngot\n%s", exp, out.String())
+		}
+	})
+}
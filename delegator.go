@@ -5,6 +5,8 @@ import (
 	"flag"
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 )
 
 // A Delegator is a parent to a set of commands. Its sole purpose is to direct
@@ -15,11 +17,37 @@ type Delegator struct {
 	Description string
 	// Flags collect and share inputs to its sub directives.
 	Flags *flag.FlagSet
+	// PersistentFlags are optional flags that are accepted at this level and
+	// by every descendant, in any position along the command path. Each
+	// descendant's flag set gets a flag that shares the same flag.Value, so a
+	// persistent flag is bound to a single variable no matter where it's
+	// specified. They're listed under "Global flags" in generated help
+	// messages. A descendant's own flag of the same name takes precedence.
+	// Values from the environment or a Config, named after this level, are
+	// applied once the whole command line is parsed.
+	PersistentFlags *flag.FlagSet
 	// Selected is the chosen transfer point of control.
 	Selected Directive
 	// Subs associates a name with another Directive. The name is what to
 	// specify from the command line.
 	Subs map[string]Directive
+	// Aliases optionally associates alternative names with the name of a
+	// subcommand. For example, {"rm": "remove"} lets a user type "rm" to
+	// select Subs["remove"]. An alias is listed next to its subcommand in help
+	// messages, but it's not a separate entry. A name in Subs takes precedence
+	// over an alias of the same name, which is then ignored.
+	Aliases map[string]string
+	// PrefixMatching lets a user select a subcommand by typing any unique
+	// prefix of its name or of an alias. When it's true, it also applies to
+	// all descendants, so setting it on a Root turns it on for the whole tree.
+	PrefixMatching bool
+	// Interspersed lets the flags of every Command below this Delegator be
+	// mixed in with the positional arguments, see Command.Interspersed. It
+	// doesn't change how the Delegator's own flags are parsed, those still
+	// come before the subcommand name.
+	Interspersed bool
+
+	middleware []Middleware
 }
 
 // Summary provides a short, one-line description.
@@ -27,56 +55,283 @@ func (d *Delegator) Summary() string { return d.Description }
 
 // Perform chooses a subcommand.
 func (d *Delegator) Perform(ctx context.Context) error {
+	fr := frameFrom(ctx)
 	args := d.Flags.Args()
 	if len(args) < 1 {
-		err := flag.ErrHelp
-		maybeCallUsage(err, d.Flags)
+		err := &missingCommandError{}
+		fr.maybeCallUsage(err, d.Flags)
 		return err
 	}
 
+	prefixMatching := d.PrefixMatching || fr.prefixMatching
+
 	var err error
+	var name string
 	switch first := args[0]; first {
 	case "-h", "-help", "--help", "help":
 		err = flag.ErrHelp
 	default:
-		if cmd, ok := d.Subs[first]; !ok {
-			err = fmt.Errorf("%w %q", errUnknownCommand, first)
-		} else {
+		var cmd Directive
+		if name, cmd, err = d.selectSub(first, prefixMatching); err == nil {
 			d.Selected = cmd
 		}
 	}
 	if err != nil {
-		maybeCallUsage(err, d.Flags)
+		fr.maybeCallUsage(err, d.Flags)
 		return err
 	}
 
+	fr = fr.child(name)
+	fr.traceCommandSelected()
+	fr.prefixMatching = prefixMatching
+	fr.interspersed = fr.interspersed || d.Interspersed
+	fr.middleware = append(fr.middleware[:len(fr.middleware):len(fr.middleware)], d.middleware...)
+	ctx = withFrame(ctx, fr)
+	perform := wrap(d.Selected, fr.middleware)
+
 	switch selected := d.Selected.(type) {
 	case *Command:
 		selected.flags = selected.Setup(*d.Flags)
-		if err = selected.flags.Parse(args[1:]); err != nil {
+		fr.interspersed = fr.interspersed || selected.Interspersed
+		if err = fr.parse(selected, selected.flags, args[1:]); err != nil {
+			return err
+		}
+		if err = fr.checkConstraints(selected.constraints(fr, selected.flags), selected.flags); err != nil {
+			fr.maybeCallUsage(err, selected.flags)
+			return err
+		}
+		if err = checkArgs(selected.Args, selected.flags.Args()); err != nil {
+			fr.maybeCallUsage(err, selected.flags)
 			return err
 		}
-		err = selected.Perform(ctx)
-		maybeCallUsage(err, selected.flags)
+		err = perform.Perform(ctx)
+		fr.maybeCallUsage(err, selected.flags)
 	case *Delegator:
 		f := selected.Flags
 		if f == nil {
 			return fmt.Errorf("selected Delegator %q requires Flags", args[0])
 		}
-		if err = f.Parse(args[1:]); err != nil {
+		if err = fr.parse(selected, f, args[1:]); err != nil {
+			return err
+		}
+		if err = fr.checkConstraints(fr.requiredFlags(f, false), f); err != nil {
+			fr.maybeCallUsage(err, f)
 			return err
 		}
-		err = selected.Perform(ctx)
+		err = perform.Perform(ctx)
 	default:
 		err = fmt.Errorf("unsupported value of type %T", selected)
 	}
 	return err
 }
 
+// selectSub finds the subcommand for a name specified from the command line.
+// The name could be an alias or, when prefixMatching is true, a unique prefix.
+// The output name is the key in Subs.
+func (d *Delegator) selectSub(input string, prefixMatching bool) (name string, cmd Directive, err error) {
+	name = input
+	if canonical, ok := d.Aliases[input]; ok {
+		if _, ok = d.Subs[input]; !ok {
+			name = canonical
+		}
+	}
+	if _, ok := d.Subs[name]; !ok && prefixMatching && name == input {
+		var candidates []string
+		if candidates, err = d.matchPrefix(input); err != nil {
+			return
+		} else if len(candidates) == 1 {
+			name = candidates[0]
+		}
+	}
+	cmd, ok := d.Subs[name]
+	if !ok && name != input {
+		err = fmt.Errorf("alias %q refers to unknown subcommand %q", input, name)
+	} else if !ok {
+		err = &UnknownCommandError{Name: input, Suggestions: d.suggest(input)}
+	}
+	return
+}
+
+// suggest finds the subcommand names and aliases that are close to the input,
+// closest first. It's for when the input is a typo. Each subcommand is
+// suggested once, by its name or by an alias, whichever is closer.
+func (d *Delegator) suggest(input string) []string {
+	type match struct {
+		word string
+		dist int
+	}
+	maxDistance := len(input)/3 + 1
+	best := make(map[string]match) // keyed by subcommand name
+	consider := func(word, name string) {
+		dist := editDistance(input, word)
+		if strings.HasPrefix(word, input) {
+			dist = 0
+		}
+		if prev, ok := best[name]; dist <= maxDistance && (!ok || dist < prev.dist) {
+			best[name] = match{word: word, dist: dist}
+		}
+	}
+	for name := range d.Subs {
+		consider(name, name)
+	}
+	for alias, name := range d.Aliases {
+		consider(alias, name)
+	}
+
+	out := make([]string, 0, len(best))
+	distances := make(map[string]int, len(best))
+	for _, m := range best {
+		out = append(out, m.word)
+		distances[m.word] = m.dist
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if distances[out[i]] != distances[out[j]] {
+			return distances[out[i]] < distances[out[j]]
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+// editDistance counts the insertions, deletions, substitutions and
+// transpositions of adjacent characters needed to turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// dist[i][j] is the distance between ra[:i] and rb[:j].
+	dist := make([][]int, len(ra)+1)
+	for i := range dist {
+		dist[i] = make([]int, len(rb)+1)
+		dist[i][0] = i
+	}
+	for j := range dist[0] {
+		dist[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			best := dist[i-1][j] + 1
+			if d := dist[i][j-1] + 1; d < best {
+				best = d
+			}
+			if d := dist[i-1][j-1] + cost; d < best {
+				best = d
+			}
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				if d := dist[i-2][j-2] + 1; d < best {
+					best = d
+				}
+			}
+			dist[i][j] = best
+		}
+	}
+	return dist[len(ra)][len(rb)]
+}
+
+// missingCommandError is returned when no subcommand is specified. It counts as
+// flag.ErrHelp, which is what used to be returned in this case.
+type missingCommandError struct{}
+
+func (e *missingCommandError) Error() string { return "missing subcommand" }
+func (e *missingCommandError) Unwrap() error { return flag.ErrHelp }
+
+// UnknownCommandError is returned when the name specified from the command line
+// does not match any subcommand.
+type UnknownCommandError struct {
+	// Name is what was specified from the command line.
+	Name string
+	// Suggestions are subcommand names or aliases similar to Name, closest
+	// first. It's empty if nothing is close enough.
+	Suggestions []string
+}
+
+func (e *UnknownCommandError) Error() string {
+	msg := fmt.Sprintf("%s %q", errUnknownCommand, e.Name)
+	switch len(e.Suggestions) {
+	case 0:
+		return msg
+	case 1:
+		return fmt.Sprintf("%s, did you mean %q?", msg, e.Suggestions[0])
+	default:
+		return fmt.Sprintf("%s, did you mean one of %s?", msg, quoteAll(e.Suggestions))
+	}
+}
+
+func (e *UnknownCommandError) Unwrap() error { return errUnknownCommand }
+
+// matchPrefix finds the subcommands with a name or alias starting with prefix.
+// It's an error if more than one subcommand matches.
+func (d *Delegator) matchPrefix(prefix string) ([]string, error) {
+	matches := make(map[string]bool)
+	for name := range d.Subs {
+		if strings.HasPrefix(name, prefix) {
+			matches[name] = true
+		}
+	}
+	for alias, name := range d.Aliases {
+		if strings.HasPrefix(alias, prefix) {
+			matches[name] = true
+		}
+	}
+	candidates := make([]string, 0, len(matches))
+	for name := range matches {
+		candidates = append(candidates, name)
+	}
+	sort.Strings(candidates)
+	if len(candidates) > 1 {
+		return nil, &AmbiguousCommandError{Name: prefix, Candidates: candidates}
+	}
+	return candidates, nil
+}
+
+// AmbiguousCommandError is returned when prefix matching is on and the name
+// specified from the command line is a prefix of more than one subcommand.
+type AmbiguousCommandError struct {
+	// Name is what was specified from the command line.
+	Name string
+	// Candidates are the names of the matching subcommands.
+	Candidates []string
+}
+
+func (e *AmbiguousCommandError) Error() string {
+	return fmt.Sprintf("ambiguous command %q, could be %s", e.Name, quoteAll(e.Candidates))
+}
+
+func quoteAll(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = strconv.Quote(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// Unwrap makes the error count as an unknown command.
+func (e *AmbiguousCommandError) Unwrap() error { return errUnknownCommand }
+
+// AliasesOf outputs the aliases of a subcommand, ordered by name. An alias that
+// has the same name as a subcommand is left out, because it selects that
+// subcommand instead.
+func (d *Delegator) AliasesOf(name string) []string {
+	var out []string
+	for alias, canonical := range d.Aliases {
+		if _, shadowed := d.Subs[alias]; canonical == name && !shadowed {
+			out = append(out, alias)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
 // DescribeSubcommands outputs summaries of each subcommand ordered by name.
+// Any aliases are listed after the subcommand name.
 func (d *Delegator) DescribeSubcommands() []string {
 	descriptions := make([]string, 0)
 	for name, subcmd := range d.Subs {
+		if aliases := d.AliasesOf(name); len(aliases) > 0 {
+			name += ", " + strings.Join(aliases, ", ")
+		}
 		descriptions = append(
 			descriptions,
 			fmt.Sprintf("%-20s\t%-40s", name, subcmd.Summary()),
@@ -0,0 +1,97 @@
+package alf
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// envBinding associates a flag with an environment variable.
+type envBinding struct {
+	flag *flag.Flag
+	name string
+}
+
+// envBindings lists the environment variables for the flags at this frame's
+// level. Inherited flags are left out, they're bound at the level where
+// they're defined. A name from an env tag, see Bind, takes precedence over one
+// derived from Root.EnvPrefix.
+func (fr *frame) envBindings(flags *flag.FlagSet) []envBinding {
+	var prefix string
+	if fr.root != nil {
+		prefix = fr.root.EnvPrefix
+	}
+	var out []envBinding
+	flags.VisitAll(func(f *flag.Flag) {
+		if fr.inherited(f) || isShortFlag(f) {
+			return
+		}
+		if name := envVar(prefix, fr.path, f); name != "" {
+			out = append(out, envBinding{flag: f, name: name})
+		}
+	})
+	return out
+}
+
+// envVar names the environment variable for a flag defined at path. It's empty
+// if the flag has none.
+func envVar(prefix string, path []string, f *flag.Flag) string {
+	if b := bindingOf(f); b != nil && b.env != "" {
+		return b.env
+	} else if prefix != "" {
+		return envName(prefix, path, f.Name)
+	}
+	return ""
+}
+
+// envName derives the name of an environment variable from the prefix, the
+// subcommand names and the flag name. For example, the prefix "MYTOOL", path
+// ["bar"] and flag "alpha" make "MYTOOL_BAR_ALPHA".
+func envName(prefix string, path []string, flagName string) string {
+	parts := append([]string{strings.TrimSuffix(prefix, "_")}, path...)
+	parts = append(parts, flagName)
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		}
+		return '_'
+	}, strings.Join(parts, "_"))
+}
+
+// applyEnv sets any flag not specified from the command line with the value of
+// its environment variable, if there is one. An empty value counts as unset.
+// Persistent flags are left to applyPersistent.
+func applyEnv(fr *frame, flags *flag.FlagSet) error {
+	bindings := fr.envBindings(flags)
+	if len(bindings) < 1 {
+		return nil
+	}
+	set := setFlags(flags)
+
+	for _, binding := range bindings {
+		if set[binding.flag.Name] || fr.isPersistent(binding.flag) {
+			continue
+		}
+		if _, err := setFromEnv(flags, binding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setFromEnv sets the flag with the value of its environment variable, and
+// reports whether there was one.
+func setFromEnv(flags *flag.FlagSet, binding envBinding) (bool, error) {
+	val := os.Getenv(binding.name)
+	if val == "" {
+		return false, nil
+	}
+	if err := flags.Set(binding.flag.Name, val); err != nil {
+		return false, fmt.Errorf("invalid value %q for environment variable %s: %w", val, binding.name, err)
+	}
+	return true, nil
+}
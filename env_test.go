@@ -0,0 +1,179 @@
+package alf_test
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+type envTestArgs struct {
+	foo     string
+	alpha   int
+	charlie string
+	ownFoo  string
+}
+
+func newEnvTestRoot(prefix string, out *bytes.Buffer, args *envTestArgs) *alf.Root {
+	rootFlags := flag.NewFlagSet("stub", flag.ContinueOnError)
+	rootFlags.SetOutput(out)
+	rootFlags.StringVar(&args.foo, "foo", "frank", "fff")
+
+	bar := alf.Delegator{
+		Description: "bar",
+		Flags:       flag.NewFlagSet("bar", flag.ContinueOnError),
+	}
+	bar.Flags.SetOutput(out)
+	bar.Flags.IntVar(&args.alpha, "alpha", 1, "aaa")
+	bar.Subs = map[string]alf.Directive{
+		"cities": &alf.Command{
+			Description: "reuses parent flags",
+			Setup: func(p flag.FlagSet) *flag.FlagSet {
+				p.Init("cities", flag.ContinueOnError)
+				p.StringVar(&args.charlie, "charlie", "parker", "ccc")
+				alf.ShortFlag(&p, "c", "charlie")
+				return &p
+			},
+			Run: func(ctx context.Context) error { return nil },
+		},
+	}
+
+	return &alf.Root{
+		Delegator: &alf.Delegator{
+			Description: "root",
+			Flags:       rootFlags,
+			Subs: map[string]alf.Directive{
+				"bar": &bar,
+				"own": &alf.Command{
+					Description: "has a flag named like a root flag",
+					Setup: func(p flag.FlagSet) *flag.FlagSet {
+						f := flag.NewFlagSet("own", flag.ContinueOnError)
+						f.SetOutput(out)
+						f.StringVar(&args.ownFoo, "foo", "otto", "ooo")
+						return f
+					},
+					Run: func(ctx context.Context) error { return nil },
+				},
+			},
+		},
+		EnvPrefix: prefix,
+	}
+}
+
+func TestEnvPrefix(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		env     map[string]string
+		args    []string
+		exp     envTestArgs
+		expErr  string
+		expHelp []string
+	}{
+		{
+			name: "defaults",
+			args: []string{"bar", "cities"},
+			exp:  envTestArgs{foo: "frank", alpha: 1, charlie: "parker"},
+		},
+		{
+			name:   "from env",
+			prefix: "STUB",
+			env:    map[string]string{"STUB_FOO": "fred", "STUB_BAR_ALPHA": "2", "STUB_BAR_CITIES_CHARLIE": "chan"},
+			args:   []string{"bar", "cities"},
+			exp:    envTestArgs{foo: "fred", alpha: 2, charlie: "chan"},
+		},
+		{
+			name:   "prefix with trailing underscore",
+			prefix: "STUB_",
+			env:    map[string]string{"STUB_FOO": "fred"},
+			args:   []string{"bar", "cities"},
+			exp:    envTestArgs{foo: "fred", alpha: 1, charlie: "parker"},
+		},
+		{
+			name:   "command line takes precedence",
+			prefix: "STUB",
+			env:    map[string]string{"STUB_FOO": "fred", "STUB_BAR_ALPHA": "2", "STUB_BAR_CITIES_CHARLIE": "chan"},
+			args:   []string{"-foo", "felix", "bar", "-alpha", "3", "cities", "-charlie", "chuck"},
+			exp:    envTestArgs{foo: "felix", alpha: 3, charlie: "chuck"},
+		},
+		{
+			name:   "short form on the command line takes precedence",
+			prefix: "STUB",
+			env:    map[string]string{"STUB_BAR_CITIES_CHARLIE": "chan"},
+			args:   []string{"bar", "cities", "-c", "chuck"},
+			exp:    envTestArgs{foo: "frank", alpha: 1, charlie: "chuck"},
+		},
+		{
+			name:   "inherited flags are bound where defined",
+			prefix: "STUB",
+			env:    map[string]string{"STUB_BAR_CITIES_ALPHA": "4"},
+			args:   []string{"bar", "-alpha", "3", "cities"},
+			exp:    envTestArgs{foo: "frank", alpha: 3, charlie: "parker"},
+		},
+		{
+			name:   "same name as an ancestor flag",
+			prefix: "STUB",
+			env:    map[string]string{"STUB_FOO": "fred", "STUB_OWN_FOO": "oscar"},
+			args:   []string{"own"},
+			exp:    envTestArgs{foo: "fred", alpha: 1, ownFoo: "oscar"},
+		},
+		{
+			name:    "same name as an ancestor flag in help",
+			prefix:  "STUB",
+			args:    []string{"own", "-h"},
+			exp:     envTestArgs{foo: "frank", alpha: 1, ownFoo: "otto"},
+			expErr:  flag.ErrHelp.Error(),
+			expHelp: []string{"STUB_OWN_FOO            \t-foo\n"},
+		},
+		{
+			name:   "ignored without prefix",
+			prefix: "",
+			env:    map[string]string{"STUB_FOO": "fred", "FOO": "fred", "_FOO": "fred"},
+			args:   []string{"bar", "cities"},
+			exp:    envTestArgs{foo: "frank", alpha: 1, charlie: "parker"},
+		},
+		{
+			name:   "invalid value",
+			prefix: "STUB",
+			env:    map[string]string{"STUB_BAR_ALPHA": "two"},
+			args:   []string{"bar", "cities"},
+			exp:    envTestArgs{foo: "frank"},
+			expErr: `invalid value "two" for environment variable STUB_BAR_ALPHA`,
+		},
+		{
+			name:    "listed in help",
+			prefix:  "STUB",
+			args:    []string{"bar", "-h"},
+			exp:     envTestArgs{foo: "frank", alpha: 1},
+			expErr:  flag.ErrHelp.Error(),
+			expHelp: []string{"Environment variables:\n\n\tSTUB_BAR_ALPHA          \t-alpha\n"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for key, val := range test.env {
+				t.Setenv(key, val)
+			}
+			var out bytes.Buffer
+			var got envTestArgs
+			err := newEnvTestRoot(test.prefix, &out, &got).Run(context.Background(), test.args)
+			if test.expErr == "" && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			} else if test.expErr != "" && (err == nil || !strings.Contains(err.Error(), test.expErr)) {
+				t.Fatalf("expected error with %q, got %v", test.expErr, err)
+			}
+			if got != test.exp {
+				t.Errorf("got %+v, expected %+v", got, test.exp)
+			}
+			for _, mention := range test.expHelp {
+				if !strings.Contains(out.String(), mention) {
+					t.Errorf("help does not contain %q\n%s", mention, out.String())
+				}
+			}
+		})
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"strings"
 
 	"github.com/rafaelespinoza/alf"
 )
@@ -31,33 +30,17 @@ var Bar = func(cmdname string) alf.Directive {
 	parentFlags := flag.NewFlagSet(_Bin+" "+cmdname, flag.ExitOnError)
 	parentFlags.IntVar(&barArgs.Alpha, "alpha", 42, "a number")
 
-	// set up help text.
-	parentFlags.Usage = func() {
-		fmt.Fprintf(parentFlags.Output(), `Usage:
-
-	%s [flags]
-
-Description:
-
-	Demo of Delegator (parent command with subcommands).
-
-Subcommands:
-
-	These will have their own set of flags. Put them after the subcommand.
-
-	%v
-
-Flags:
-
-`, parentFlags.Name(), strings.Join(del.DescribeSubcommands(), "\n\t"))
-		parentFlags.PrintDefaults()
-	}
+	// A help message is generated because this flag set doesn't have its own
+	// Usage func. It shows the subcommands and the flags.
 	del.Flags = parentFlags // share flag data from parent to child command.
 
 	// define subcommands here. The key is the subcommand name.
 	del.Subs = map[string]alf.Directive{
 		"cities": &alf.Command{
 			Description: "print a city name",
+			// Accept flags after positional arguments too, so the -bravo in
+			// "bar cities x -bravo" is a flag rather than an argument.
+			Interspersed: true,
 			// Setup can be used to generate documentation and to define an
 			// independent flag set for the subcommand.
 			Setup: func(inFlags flag.FlagSet) *flag.FlagSet {
@@ -65,22 +48,9 @@ Flags:
 				inFlags.Init(name, flag.ExitOnError)
 				inFlags.BoolVar(&barArgs.Bravo, "bravo", false, "show a city with a B")
 				inFlags.StringVar(&barArgs.Charlie, "charlie", "parker", "customize charlie")
-
-				// help text for subcommand.
-				inFlags.Usage = func() {
-					fmt.Fprintf(inFlags.Output(), `Usage:
-
-	%s [flags]
-
-Description:
-
-	Output a city name. Here are some flags.
-
-Flags:
-
-`, inFlags.Name())
-					inFlags.PrintDefaults()
-				}
+				// Short forms can be clustered, as in "-bc bird".
+				alf.ShortFlag(&inFlags, "b", "bravo")
+				alf.ShortFlag(&inFlags, "c", "charlie")
 				return &inFlags
 			},
 			// By now, the flags have been parsed and the subcommand is ready to
@@ -103,7 +73,7 @@ Flags:
 					}
 				}
 				ind := barArgs.Alpha % len(cities)
-				fmt.Printf(
+				fmt.Fprintf(alf.Stdout(ctx),
 					"city: %q, custom charlie: %q\n",
 					cities[ind], barArgs.Charlie,
 				)
@@ -117,27 +87,16 @@ Flags:
 				inFlags.Init(name, flag.ExitOnError)
 				inFlags.BoolVar(&barArgs.Bravo, "bravo", false, "return an error if true")
 				inFlags.StringVar(&barArgs.Charlie, "chuck", "berry", "an alternative charlie")
-				inFlags.Usage = func() {
-					fmt.Fprintf(inFlags.Output(), `Usage:
-
-	%s [flags]
-
-Description:
-
-	Return an error if bravo is true, otherwise be ok.
-
-Flags:
-
-`, inFlags.Name())
-					inFlags.PrintDefaults()
-				}
 				return &inFlags
 			},
+			// Constraints are checked after parsing and before Run. They're
+			// also listed in the help.
+			Constraints: []alf.Constraint{alf.MutuallyExclusive("bravo", "chuck")},
 			Run: func(ctx context.Context) error {
 				if barArgs.Bravo {
 					return fmt.Errorf("demo force show usage%w", alf.ErrShowUsage)
 				}
-				fmt.Printf("your alternative charlie %q is %d years old\n", barArgs.Charlie, barArgs.Alpha)
+				fmt.Fprintf(alf.Stdout(ctx), "your alternative charlie %q is %d years old\n", barArgs.Charlie, barArgs.Alpha)
 				return nil
 			},
 		},
@@ -150,35 +109,16 @@ Flags:
 		Description: "a subcommand (with its own commands) of a subcommand",
 		Flags:       flag.NewFlagSet(parentFlags.Name()+" nested", flag.ContinueOnError),
 	}
-	nested.Flags.Usage = func() {
-		fmt.Fprintf(nested.Flags.Output(), `Usage:
-
-	%s [flags]
-
-Description:
-
-	Demo of nested Delegator (subcommand has a parent and its own subcommands).
-
-Subcommands:
-
-	%v
-
-Flags:
-
-`, nested.Flags.Name(), strings.Join(nested.DescribeSubcommands(), "\n\t"))
-		nested.Flags.PrintDefaults()
-	}
 	nested.Subs = map[string]alf.Directive{
 		"alfa": &alf.Command{
 			Description: "terminal command of a nested subcommand",
 			Setup: func(inFlags flag.FlagSet) *flag.FlagSet {
 				name := nested.Flags.Name() + " alfa"
 				inFlags.Init(name, flag.ContinueOnError)
-				inFlags.Usage = func() { fmt.Fprintf(inFlags.Output(), "help for %s\n", inFlags.Name()) }
 				return &inFlags
 			},
 			Run: func(ctx context.Context) error {
-				fmt.Println("called bar.nested.alfa")
+				fmt.Fprintln(alf.Stdout(ctx), "called bar.nested.alfa")
 				return nil
 			},
 		},
@@ -187,16 +127,18 @@ Flags:
 			Setup: func(inFlags flag.FlagSet) *flag.FlagSet {
 				name := nested.Flags.Name() + " bravo"
 				inFlags.Init(name, flag.ContinueOnError)
-				inFlags.Usage = func() { fmt.Fprintf(inFlags.Output(), "help for %s\n", inFlags.Name()) }
 				return &inFlags
 			},
 			Run: func(ctx context.Context) error {
-				fmt.Println("called bar.nested.bravo")
+				fmt.Fprintln(alf.Stdout(ctx), "called bar.nested.bravo")
 				return errors.New("demo error")
 			},
 		},
 	}
 	del.Subs["nested"] = &nested
 
+	// Aliases are alternative names for subcommands.
+	del.Aliases = map[string]string{"city": "cities"}
+
 	return del
 }("bar")
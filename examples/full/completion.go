@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+// Completion outputs a shell completion script for this program. Try it out in
+// bash with:
+//
+//	source <(full_example completion bash)
+var Completion = func() alf.Directive {
+	// Keep a reference to the flag set so Run can read the positional args.
+	var flags *flag.FlagSet
+
+	return &alf.Command{
+		Description: "output a completion script for bash, zsh or fish",
+		Setup: func(inFlags flag.FlagSet) *flag.FlagSet {
+			flags = flag.NewFlagSet(_Bin+" completion", flag.ExitOnError)
+			return flags
+		},
+		// Args describes the positional args. They're checked before Run and
+		// shown in the help, like "completion [flags] <shell>".
+		Args: []alf.Arg{{Name: "shell", Description: "bash, zsh or fish"}},
+		// Complete suggests values for the positional arg when the program's
+		// completion script asks for them.
+		Complete: func(ctx context.Context, args []string, toComplete string) []string {
+			if len(args) > 0 {
+				return nil
+			}
+			return []string{alf.ShellBash, alf.ShellZsh, alf.ShellFish}
+		},
+		Run: func(ctx context.Context) error {
+			return Root.WriteCompletion(alf.Stdout(ctx), flags.Arg(0))
+		},
+	}
+}()
@@ -4,14 +4,18 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"strings"
 
 	"github.com/rafaelespinoza/alf"
 )
 
-// fooArgs is named args for the "foo" command.
+// fooArgs is named args for the "foo" command. The struct tags describe the
+// flags, see alf.Bind.
 var fooArgs struct {
-	Delta int
-	Echo  string
+	Delta int    `flag:"delta" default:"5" usage:"repeat a string delta times"`
+	Echo  string `flag:"echo" default:"test" usage:"string to repeat"`
+	// The help lists the choices, and other values are rejected.
+	Case string `flag:"case" default:"as-is" choices:"as-is,lower,upper" usage:"change the case of the string"`
 }
 
 const maxDelta = 42
@@ -23,8 +27,7 @@ var Foo alf.Directive = &alf.Command{
 	Setup: func(inFlags flag.FlagSet) *flag.FlagSet {
 		name := _Bin + " foo"
 		flags := flag.NewFlagSet(name, flag.ExitOnError)
-		flags.IntVar(&fooArgs.Delta, "delta", 5, "repeat a string delta times")
-		flags.StringVar(&fooArgs.Echo, "echo", "test", "string to repeat")
+		alf.Bind(flags, &fooArgs)
 		flags.Usage = func() {
 			fmt.Fprintf(flags.Output(), `Usage:
 
@@ -51,8 +54,17 @@ Flags:
 				fooArgs.Delta, maxDelta, alf.ErrShowUsage,
 			)
 		}
+		echo := fooArgs.Echo
+		switch fooArgs.Case {
+		case "lower":
+			echo = strings.ToLower(echo)
+		case "upper":
+			echo = strings.ToUpper(echo)
+		}
+		// Write to alf.Stdout rather than os.Stdout, so the output can be
+		// redirected by setting Root.Out, such as in a test.
 		for i := 0; i < fooArgs.Delta; i++ {
-			fmt.Println(fooArgs.Echo)
+			fmt.Fprintln(alf.Stdout(ctx), echo)
 		}
 		return nil
 	},
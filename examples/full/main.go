@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/rafaelespinoza/alf"
 )
@@ -20,6 +22,12 @@ var (
 
 	// _ShowPrePerform helps demo the Root.PrePerform field.
 	_ShowPrePerform bool
+
+	// _ConfigFile helps demo the Root.LoadConfig field.
+	_ConfigFile string
+
+	// _ShowTiming helps demo middleware.
+	_ShowTiming bool
 )
 
 func init() {
@@ -32,62 +40,90 @@ func init() {
 		// performs a task (a Command) or something that passes control to its
 		// own subcommands (a Delegator).
 		Subs: map[string]alf.Directive{
-			"foo": Foo,
-			"bar": Bar,
+			"foo":        Foo,
+			"bar":        Bar,
+			"completion": Completion,
 		},
 		// Build a plain old flag set from the standard library.
-		Flags: flag.NewFlagSet("root", flag.ExitOnError),
+		Flags: flag.NewFlagSet(_Bin, flag.ExitOnError),
+		// Accept unique prefixes of subcommand names throughout the tree, so
+		// "bar nest al" is the same as "bar nested alfa".
+		PrefixMatching: true,
 	}
 	del.Flags.BoolVar(&_ShowPrePerform, "pre", false, "if true, log a message in Root.PrePerform")
+	del.Flags.StringVar(&_ConfigFile, "config", "", "path to a .json or .ini file with flag values")
 
-	// Add a help message.
-	del.Flags.Usage = func() {
-		fmt.Fprintf(del.Flags.Output(), `Usage:
-	%s [flags] subcommand [subflags]
-
-Description:
-
-	%s is a demo of %s.
-
-Subcommands:
+	// Persistent flags are accepted at any position in the command path, for
+	// example "bar -timing cities" or "bar cities -timing".
+	del.PersistentFlags = flag.NewFlagSet(_Bin, flag.ExitOnError)
+	del.PersistentFlags.BoolVar(&_ShowTiming, "timing", false, "if true, log how long the command took")
 
-	%v
-
-Examples:
-
-	%s [subcommand] -h
-
-Flags:
-
-`,
-			_Bin, _Bin, pkg, strings.Join(Root.DescribeSubcommands(), "\n\t"), _Bin)
-
-		del.Flags.PrintDefaults()
-	}
+	// There's no need to set del.Flags.Usage. A help message is generated for
+	// any flag set without its own Usage func.
 
 	// The root command directs you to other delegators and commands.
 	Root = &alf.Root{
 		Delegator: del,
+		// Flags not specified on the command line can be set from environment
+		// variables. Try running with FULL_BAR_ALPHA=3.
+		EnvPrefix: "FULL",
+		// Flags not specified on the command line or in the environment can
+		// be set from a config file. Sections are command paths, like
+		// [bar.cities], and keys are flag names.
+		LoadConfig: func(ctx context.Context) (alf.Config, error) {
+			if _ConfigFile == "" {
+				return nil, nil
+			}
+			return alf.ReadConfigFile(_ConfigFile)
+		},
+		// Turn a panic in any command into an error with a friendly message.
+		// The stack trace is shown with the -debug flag or ALF_DEBUG=1.
+		RecoverPanics: true,
+		// Cancel the context passed to commands on Ctrl+C or SIGTERM. Another
+		// signal, or waiting longer than the grace period, exits right away.
+		Signals:     []os.Signal{os.Interrupt, syscall.SIGTERM},
+		GracePeriod: 5 * time.Second,
 		// This field is an optional function to invoke after the flags have
 		// been parsed, but before choosing a subcommand.
 		PrePerform: func(ctx context.Context) error {
 			if _ShowPrePerform {
-				fmt.Println("called Root.PrePerform")
+				fmt.Fprintln(alf.Stdout(ctx), "called Root.PrePerform")
 			}
 			return nil
 		},
 	}
+
+	del.PersistentFlags.BoolVar(&Root.Debug, "debug", false, "if true, show stack traces of recovered panics")
+
+	// Middleware wraps the Perform method of every subcommand in the tree.
+	Root.Use(timing)
+}
+
+// timing is middleware that logs how long a command took to run.
+func timing(next alf.Directive) alf.Directive {
+	if _, ok := next.(*alf.Command); !ok {
+		return next // only time the commands, not the delegators.
+	}
+	return alf.WrapPerform(next, func(ctx context.Context) error {
+		start := time.Now()
+		err := next.Perform(ctx)
+		if _ShowTiming {
+			// The Invocation describes the command path, the flags and
+			// the positional args.
+			inv := alf.InvocationFrom(ctx)
+			fmt.Fprintf(alf.Stdout(ctx), "%s %q took %v\n", strings.Join(inv.Path, " "), inv.Positional, time.Since(start))
+		}
+		return err
+	})
 }
 
 func main() {
-	// Invoke the (*Root).Run method to execute your top-level command. It
-	// parses input flags and goes from there.
+	// Invoke the (*Root).Main method to execute your top-level command. It
+	// runs with os.Args[1:], outputs any error to stderr and exits with a
+	// conventional status: 0 for help, 2 for usage errors and 1 otherwise.
 	//
-	// NOTE: when passing positional arguments, be sure to reslice it from
-	// [1:]. At this point, os.Args[0] is the binary itself. Omit this value
-	// when starting your application.
-	if err := Root.Run(context.Background(), os.Args[1:]); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
-	}
+	// To handle errors yourself, call (*Root).Run instead. NOTE: when passing
+	// positional arguments, be sure to reslice it from [1:]. At this point,
+	// os.Args[0] is the binary itself.
+	Root.Main(context.Background())
 }
@@ -0,0 +1,89 @@
+package alf
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ExitCoder is an error that decides the exit status of the program, see
+// Root.Main.
+type ExitCoder interface {
+	error
+	ExitCode() int
+}
+
+// Main is an entry point for a program. It runs the command with os.Args[1:],
+// outputs any error to stderr, or Err if it's set, and exits. Call it at the
+// end of func main.
+//
+// The exit status follows the usual conventions:
+//   - 0 for success or when help was explicitly requested.
+//   - 2 for invalid usage, such as a flag parsing error, an unknown or missing
+//     subcommand, or an error wrapping ErrShowUsage.
+//   - the ExitCode of an error implementing ExitCoder.
+//   - 1 for any other error.
+//
+// Each error is output once. Some have already been reported by the time Run
+// returns, like flag parsing errors and recovered panics, so those are not
+// repeated.
+func (r *Root) Main(ctx context.Context) {
+	err := r.Run(ctx, os.Args[1:])
+	osExit(r.report(r.stderr(), err))
+}
+
+// report outputs the error, unless it's already been reported, and decides the
+// exit status.
+func (r *Root) report(w io.Writer, err error) (code int) {
+	if err == nil {
+		return 0
+	}
+	var (
+		coder   ExitCoder
+		perr    *PanicError
+		parsing *parseError
+		missing *missingCommandError
+	)
+	switch {
+	case errors.As(err, &coder):
+		code = coder.ExitCode()
+	case errors.As(err, &perr):
+		return 1
+	case errors.As(err, &missing):
+		code = 2
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.As(err, &parsing):
+		return 2
+	case errors.Is(err, ErrShowUsage), errors.Is(err, errUnknownCommand):
+		code = 2
+	default:
+		code = 1
+	}
+
+	if msg := errorMessage(err); msg != "" {
+		fmt.Fprintf(w, "%s: %s\n", r.name(), msg)
+	}
+	return
+}
+
+// errorMessage is the text of the error, without the punctuation around the
+// empty text of ErrShowUsage. For example, fmt.Errorf("%w: bad input",
+// ErrShowUsage) is just "bad input". The suggestions of an UnknownCommandError
+// are left out, they were already shown after the usage.
+func errorMessage(err error) string {
+	msg := err.Error()
+	if errors.Is(err, ErrShowUsage) {
+		msg = strings.Trim(msg, " :,;")
+	}
+	var unknown *UnknownCommandError
+	if errors.As(err, &unknown) && len(unknown.Suggestions) > 0 {
+		short := UnknownCommandError{Name: unknown.Name}
+		msg = strings.Replace(msg, unknown.Error(), short.Error(), 1)
+	}
+	return msg
+}
@@ -0,0 +1,89 @@
+package alf_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+type stubExitError struct{ code int }
+
+func (e stubExitError) Error() string { return fmt.Sprintf("exit %d", e.code) }
+func (e stubExitError) ExitCode() int { return e.code }
+
+func TestRootMain(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		prePerform func(ctx context.Context) error
+		expCode    int
+		expStderr  string
+	}{
+		{name: "ok", args: []string{"alpha"}, expCode: 0},
+		{name: "help", args: []string{"-h"}, expCode: 0},
+		{name: "subcommand help", args: []string{"delta", "help"}, expCode: 0},
+		{name: "command help", args: []string{"delta", "foxtrot", "-h"}, expCode: 0},
+		{name: "missing subcommand", args: []string{}, expCode: 2, expStderr: "root: missing subcommand\n"},
+		{name: "unknown flag", args: []string{"-nope"}, expCode: 2},
+		{name: "invalid flag value", args: []string{"delta", "-bar", "x", "echo"}, expCode: 2},
+		{name: "unknown command", args: []string{"echo"}, expCode: 2, expStderr: "root: unknown command \"echo\"\n"},
+		{name: "unknown command with suggestion", args: []string{"delt"}, expCode: 2, expStderr: "root: unknown command \"delt\"\n"},
+		{name: "show usage", args: []string{"charlie"}, expCode: 2},
+		{
+			name:       "show usage with message",
+			args:       []string{"alpha"},
+			prePerform: func(ctx context.Context) error { return fmt.Errorf("%w, wrap", alf.ErrShowUsage) },
+			expCode:    2,
+			expStderr:  "root: wrap\n",
+		},
+		{
+			name:       "show usage with prefix",
+			args:       []string{"alpha"},
+			prePerform: func(ctx context.Context) error { return fmt.Errorf("bad input: %w", alf.ErrShowUsage) },
+			expCode:    2,
+			expStderr:  "root: bad input\n",
+		},
+		{name: "error", args: []string{"bravo"}, expCode: 1, expStderr: "root: oof\n"},
+		{
+			name:       "ExitCoder",
+			args:       []string{"alpha"},
+			prePerform: func(ctx context.Context) error { return fmt.Errorf("wrapped: %w", stubExitError{code: 3}) },
+			expCode:    3,
+			expStderr:  "root: wrapped: exit 3\n",
+		},
+		{
+			name:       "panic",
+			args:       []string{"alpha"},
+			prePerform: func(ctx context.Context) error { panic("boom") },
+			expCode:    1,
+			expStderr:  "root: internal error: boom\nThis is a bug. Set ALF_DEBUG=1 to see the stack trace.\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(alf.DebugEnv, "")
+			root := newStubRoot("root", new(string), nil)
+			root.PrePerform = test.prePerform
+			root.RecoverPanics = true
+
+			origArgs := os.Args
+			os.Args = append([]string{"root"}, test.args...)
+			defer func() { os.Args = origArgs }()
+
+			code := -1
+			defer alf.SetOsExit(func(c int) { code = c })()
+			stderr := captureFile(t, &os.Stderr, func() { root.Main(context.Background()) })
+
+			if code != test.expCode {
+				t.Errorf("wrong exit code; got %d, expected %d", code, test.expCode)
+			}
+			if stderr != test.expStderr {
+				t.Errorf("wrong stderr\ngot      %q\nexpected %q", stderr, test.expStderr)
+			}
+		})
+	}
+}
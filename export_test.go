@@ -0,0 +1,9 @@
+package alf
+
+// SetOsExit replaces the func used to exit the program, and returns a func to
+// restore it.
+func SetOsExit(fn func(code int)) (restore func()) {
+	orig := osExit
+	osExit = fn
+	return func() { osExit = orig }
+}
@@ -0,0 +1,108 @@
+package alf
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A frame describes where a Directive is in the command tree and carries
+// settings inherited from its ancestors. Root.Run starts one, each Delegator
+// passes a copy with its own additions down to the selected subcommand.
+type frame struct {
+	root *Root
+	// prog is the program name.
+	prog string
+	// path is the sequence of subcommand names taken to get here.
+	path []string
+	// ancestorFlags has the flags defined by ancestors.
+	ancestorFlags []*flag.Flag
+	// ownFlags has the flags at this level. It's noted when the flags are
+	// parsed.
+	ownFlags []*flag.Flag
+	// prefixMatching is inherited from Delegator.PrefixMatching.
+	prefixMatching bool
+	// interspersed is inherited from Delegator.Interspersed, or set by
+	// Command.Interspersed.
+	interspersed bool
+	// config is from Root.LoadConfig.
+	config Config
+	// middleware accumulates from each Delegator along the path, see
+	// (*Delegator).Use.
+	middleware []Middleware
+	// persistent accumulates from each Delegator's PersistentFlags along the
+	// path.
+	persistent []*flag.Flag
+	// flags has the flag set of each level along the path, including this
+	// one once it's parsed.
+	flags []*flag.FlagSet
+	// trace is from WithTrace.
+	trace *Trace
+	// run is shared by all frames of one call to Root.Run.
+	run *runState
+}
+
+// runState keeps track of one call to Root.Run. Unlike a frame, which describes
+// one level, it's updated as the command path goes deeper.
+type runState struct {
+	// args are the arguments to Root.Run.
+	args []string
+	// path is the deepest path reached so far.
+	path []string
+	// outputs has the previous output of each flag set that was pointed to
+	// Root.Err, to put back when Run returns.
+	outputs map[*flag.FlagSet]io.Writer
+}
+
+// setOutput points the output of flags to w until restoreOutputs is called.
+func (s *runState) setOutput(flags *flag.FlagSet, w io.Writer) {
+	if s.outputs == nil {
+		s.outputs = make(map[*flag.FlagSet]io.Writer)
+	}
+	if _, ok := s.outputs[flags]; !ok {
+		s.outputs[flags] = flags.Output()
+	}
+	flags.SetOutput(w)
+}
+
+func (s *runState) restoreOutputs() {
+	for flags, w := range s.outputs {
+		flags.SetOutput(w)
+	}
+}
+
+type frameKey struct{}
+
+// frameFrom gets the frame from the context. If there isn't one, such as when
+// a Delegator's Perform method is called without a Root, then it's a frame for
+// the top of the command tree.
+func frameFrom(ctx context.Context) *frame {
+	if fr, ok := ctx.Value(frameKey{}).(*frame); ok {
+		return fr
+	}
+	return &frame{prog: filepath.Base(os.Args[0]), trace: traceFrom(ctx)}
+}
+
+func withFrame(ctx context.Context, fr *frame) context.Context {
+	return context.WithValue(ctx, frameKey{}, fr)
+}
+
+// child makes a frame for a subcommand.
+func (fr *frame) child(name string) *frame {
+	out := *fr
+	out.path = append(fr.path[:len(fr.path):len(fr.path)], name)
+	out.ancestorFlags = append(fr.ancestorFlags[:len(fr.ancestorFlags):len(fr.ancestorFlags)], fr.ownFlags...)
+	out.ownFlags = nil
+	if out.run != nil {
+		out.run.path = out.path
+	}
+	return &out
+}
+
+// commandPath is the program name followed by the subcommand names.
+func (fr *frame) commandPath() string {
+	return strings.Join(append([]string{fr.prog}, fr.path...), " ")
+}
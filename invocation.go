@@ -0,0 +1,75 @@
+package alf
+
+import (
+	"context"
+	"flag"
+)
+
+// An Invocation describes how the program was invoked, as far down the command
+// path as it's gone. Get one with InvocationFrom.
+type Invocation struct {
+	// Args are the arguments to Root.Run, usually os.Args[1:]. They're nil
+	// without a Root.
+	Args []string
+	// Path is the names of the subcommands selected so far, like CommandPath.
+	Path []string
+	// Flags has the parsed flag set of each level of the command path,
+	// starting with the Root's. Within a Command's Run func, the last one is
+	// the Command's.
+	Flags []*flag.FlagSet
+	// Positional are the arguments left over after parsing the last flag set.
+	// For a Command, these are its positional arguments. For a Delegator, they
+	// start with the name of the selected subcommand.
+	Positional []string
+}
+
+// InvocationFrom gets the Invocation for the context passed to a Directive's
+// Perform method by Root.Run or a Delegator, such as the one for a Command's
+// Run func or a Middleware. It's nil if the context didn't come from either.
+func InvocationFrom(ctx context.Context) *Invocation {
+	fr, ok := ctx.Value(frameKey{}).(*frame)
+	if !ok {
+		return nil
+	}
+	out := &Invocation{
+		Path:  append(make([]string, 0, len(fr.path)), fr.path...),
+		Flags: append(make([]*flag.FlagSet, 0, len(fr.flags)), fr.flags...),
+	}
+	if fr.run != nil {
+		out.Args = fr.run.args
+	}
+	if n := len(fr.flags); n > 0 {
+		out.Positional = fr.flags[n-1].Args()
+	}
+	return out
+}
+
+// IsSet reports whether the named flag was set. Like with Constraints, a flag
+// counts as set if it's specified on the command line, or comes from an
+// environment variable or a Config, but not if it just has its default value.
+// Specifying the short form of a flag, see ShortFlag, counts as setting the
+// flag, and either form may be passed as the name.
+//
+// The name refers to the flag in the deepest level that defines it, so a flag
+// of an ancestor with the same name doesn't count. The same flag at several
+// levels, such as a persistent flag or one in a flag set copied from a parent,
+// counts as set if it was set at any of them.
+func (inv *Invocation) IsSet(name string) bool {
+	var target *flag.Flag
+	for i := len(inv.Flags) - 1; i >= 0 && target == nil; i-- {
+		if target = inv.Flags[i].Lookup(name); target != nil {
+			if short, ok := target.Value.(*shortValue); ok {
+				target = inv.Flags[i].Lookup(short.long)
+			}
+		}
+	}
+	if target == nil {
+		return false
+	}
+	for _, flags := range inv.Flags {
+		if f := flags.Lookup(target.Name); f != nil && sameValue(f.Value, target.Value) && setFlags(flags)[f.Name] {
+			return true
+		}
+	}
+	return false
+}
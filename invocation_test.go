@@ -0,0 +1,111 @@
+package alf_test
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+func TestInvocationFrom(t *testing.T) {
+	var (
+		got     *alf.Invocation
+		fromMid *alf.Invocation
+		verbose bool
+		alpha   int
+		bravo   bool
+		timing  bool
+		name    string
+	)
+	nested := &alf.Delegator{
+		Description: "nested",
+		Flags:       newMutedFlagSet("nested", flag.ContinueOnError),
+		Subs: map[string]alf.Directive{
+			"alfa": &alf.Command{
+				Description: "terminal",
+				Setup: func(p flag.FlagSet) *flag.FlagSet {
+					p.Init("alfa", flag.ContinueOnError)
+					p.BoolVar(&verbose, "verbose", false, "vvv")
+					p.BoolVar(&bravo, "bravo", false, "bbb")
+					p.StringVar(&name, "name", "", "its own")
+					alf.ShortFlag(&p, "v", "verbose")
+					return &p
+				},
+				Run: func(ctx context.Context) error {
+					got = alf.InvocationFrom(ctx)
+					return nil
+				},
+			},
+		},
+	}
+	nested.Flags.IntVar(&alpha, "alpha", 1, "aaa")
+	nested.Use(func(next alf.Directive) alf.Directive {
+		return alf.WrapPerform(next, func(ctx context.Context) error {
+			fromMid = alf.InvocationFrom(ctx)
+			return next.Perform(ctx)
+		})
+	})
+
+	root := &alf.Root{
+		Delegator: &alf.Delegator{
+			Description:     "root",
+			Flags:           newMutedFlagSet("stub", flag.ContinueOnError),
+			PersistentFlags: newMutedFlagSet("stub", flag.ContinueOnError),
+			Subs:            map[string]alf.Directive{"nested": nested},
+		},
+		EnvPrefix: "STUB",
+	}
+	root.PersistentFlags.BoolVar(&timing, "timing", false, "ttt")
+	root.Flags.String("name", "", "same name as the Command's flag")
+	t.Setenv("STUB_NESTED_ALFA_BRAVO", "true")
+
+	args := []string{"-timing", "-name", "root", "nested", "alfa", "-v", "x", "y"}
+	if err := root.Run(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+	if got == nil {
+		t.Fatal("expected an Invocation, got nil")
+	}
+
+	if !reflect.DeepEqual(got.Args, args) {
+		t.Errorf("wrong Args; got %q, expected %q", got.Args, args)
+	}
+	if exp := []string{"nested", "alfa"}; !reflect.DeepEqual(got.Path, exp) {
+		t.Errorf("wrong Path; got %q, expected %q", got.Path, exp)
+	}
+	if len(got.Flags) != 3 {
+		t.Fatalf("wrong number of flag sets; got %d, expected 3", len(got.Flags))
+	}
+	if got.Flags[0] != root.Flags || got.Flags[1] != nested.Flags {
+		t.Errorf("wrong flag sets; got %v", got.Flags)
+	}
+	if exp := []string{"x", "y"}; !reflect.DeepEqual(got.Positional, exp) {
+		t.Errorf("wrong Positional; got %q, expected %q", got.Positional, exp)
+	}
+	for name, exp := range map[string]bool{
+		"timing":  true, // persistent, at the top level
+		"verbose": true, // by its short form
+		"bravo":   true, // by an environment variable
+		"v":       true,
+		"alpha":   false,
+		"name":    false, // only the top-level flag of the same name
+		"nope":    false,
+	} {
+		if got.IsSet(name) != exp {
+			t.Errorf("IsSet(%q) should be %t", name, exp)
+		}
+	}
+
+	if fromMid == nil {
+		t.Fatal("expected an Invocation in middleware, got nil")
+	}
+	if exp := []string{"nested", "alfa"}; !reflect.DeepEqual(fromMid.Path, exp) {
+		t.Errorf("wrong Path in middleware; got %q, expected %q", fromMid.Path, exp)
+	}
+
+	if inv := alf.InvocationFrom(context.Background()); inv != nil {
+		t.Errorf("expected nil without a Root, got %+v", inv)
+	}
+}
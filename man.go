@@ -0,0 +1,161 @@
+package alf
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// manSection is the manual section for commands.
+const manSection = "1"
+
+// WriteManPages renders a roff man page for every command path in the tree,
+// and writes each one to a file in dir. Pages are named after the command path
+// joined by hyphens, for example "bin.1", "bin-bar.1" and "bin-bar-nested.1".
+// Each page lists the flags with their defaults, and refers to its parent and
+// children in the SEE ALSO section.
+//
+// Writing the pages calls the Setup func of every Command in the tree, see
+// Command.Setup.
+func (r *Root) WriteManPages(dir string) error {
+	prog := r.name()
+	return Walk(r, func(path []string, d Directive, flags *flag.FlagSet) (err error) {
+		page := manPageName(prog, path)
+		file, err := os.Create(filepath.Join(dir, page+"."+manSection))
+		if err != nil {
+			return
+		}
+		defer func() {
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+		}()
+
+		w := bufio.NewWriter(file)
+		writeManPage(w, prog, path, d, flags)
+		return w.Flush()
+	})
+}
+
+func manPageName(prog string, path []string) string {
+	return strings.Join(append([]string{prog}, path...), "-")
+}
+
+func writeManPage(w io.Writer, prog string, path []string, d Directive, flags *flag.FlagSet) {
+	page := manPageName(prog, path)
+	cmd := strings.Join(append([]string{prog}, path...), " ")
+	del, isDelegator := d.(*Delegator)
+
+	fmt.Fprintf(w, ".TH %q %q \"\" \"\" \"\"\n", strings.ToUpper(page), manSection)
+
+	fmt.Fprintln(w, ".SH NAME")
+	fmt.Fprintf(w, "%s \\- %s\n", roffEscape(page), roffEscape(firstLine(d.Summary())))
+
+	fmt.Fprintln(w, ".SH SYNOPSIS")
+	fmt.Fprintf(w, ".B %s\n", roffEscape(cmd))
+	if isDelegator {
+		fmt.Fprintln(w, `[\fIflags\fR] \fIsubcommand\fR [\fIsubflags\fR]`)
+	} else if cmd, ok := d.(*Command); ok && cmd.Args != nil {
+		fmt.Fprintf(w, "[\\fIflags\\fR] %s\n", roffEscape(argsSynopsis(cmd.Args)))
+	} else {
+		fmt.Fprintln(w, `[\fIflags\fR] [\fIargs\fR]`)
+	}
+
+	if desc := strings.TrimSpace(d.Summary()); desc != "" {
+		fmt.Fprintln(w, ".SH DESCRIPTION")
+		writeRoffText(w, desc)
+	}
+
+	if flags != nil && hasFlags(flags) {
+		fmt.Fprintln(w, ".SH OPTIONS")
+		shorts := shortFlags(flags)
+		flags.VisitAll(func(f *flag.Flag) {
+			if isShortFlag(f) {
+				return
+			}
+			typeName, usage := unquoteUsage(f)
+			names := roffEscape(flagNames(f, shorts[f.Name]))
+			if strings.Contains(names, " ") {
+				names = `"` + names + `"`
+			}
+			fmt.Fprintln(w, ".TP")
+			if typeName != "" {
+				fmt.Fprintf(w, ".BI %s \" %s\"\n", names, roffEscape(typeName))
+			} else {
+				fmt.Fprintf(w, ".B %s\n", names)
+			}
+			if def, ok := flagDefault(f); ok {
+				usage += fmt.Sprintf(" (default %s)", def)
+			}
+			writeRoffText(w, usage)
+		})
+	}
+
+	var seeAlso []string
+	if len(path) > 0 {
+		seeAlso = append(seeAlso, manPageName(prog, path[:len(path)-1]))
+	}
+	if isDelegator && len(del.Subs) > 0 {
+		fmt.Fprintln(w, ".SH COMMANDS")
+		for _, name := range sortedKeys(del.Subs) {
+			fmt.Fprintln(w, ".TP")
+			fmt.Fprintf(w, ".B %s\n", roffEscape(strings.Join(append([]string{name}, del.AliasesOf(name)...), ", ")))
+			writeRoffText(w, firstLine(del.Subs[name].Summary()))
+			seeAlso = append(seeAlso, manPageName(prog, append(path[:len(path):len(path)], name)))
+		}
+	}
+
+	if len(seeAlso) > 0 {
+		fmt.Fprintln(w, ".SH SEE ALSO")
+		for i, name := range seeAlso {
+			sep := ","
+			if i == len(seeAlso)-1 {
+				sep = ""
+			}
+			fmt.Fprintf(w, ".BR %s (%s)%s\n", roffEscape(name), manSection, sep)
+		}
+	}
+}
+
+// writeRoffText outputs a paragraph of text, one escaped line at a time.
+func writeRoffText(w io.Writer, text string) {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			fmt.Fprintln(w, ".sp")
+			continue
+		}
+		fmt.Fprintln(w, roffEscape(line))
+	}
+}
+
+// roffEscape makes text safe to put in a roff document.
+func roffEscape(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`).Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
+
+func hasFlags(flags *flag.FlagSet) (ok bool) {
+	flags.VisitAll(func(*flag.Flag) { ok = true })
+	return
+}
+
+// flagDefault returns the default value of a flag in the format used by
+// (*flag.FlagSet).PrintDefaults. It's not ok if the default is a zero value.
+func flagDefault(f *flag.Flag) (string, bool) {
+	switch f.DefValue {
+	case "", "0", "false", "[]", "<nil>":
+		return "", false
+	}
+	if typeName, _ := unquoteUsage(f); typeName == "string" {
+		return fmt.Sprintf("%q", f.DefValue), true
+	}
+	return f.DefValue, true
+}
@@ -0,0 +1,53 @@
+package alf_test
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestRootWriteManPages(t *testing.T) {
+	root := newStubRoot("stub", nil, nil)
+	dir := t.TempDir()
+	if err := root.WriteManPages(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	sort.Strings(got)
+	expected := []string{
+		"stub-alpha.1", "stub-bravo.1", "stub-charlie.1",
+		"stub-delta-echo.1", "stub-delta-foxtrot.1", "stub-delta-golf.1", "stub-delta-hotel.1",
+		"stub-delta-india-bar.1", "stub-delta-india-foo.1", "stub-delta-india.1",
+		"stub-delta.1", "stub.1",
+	}
+	if strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Fatalf("wrong files\ngot      %q\nexpected %q", got, expected)
+	}
+
+	page, err := os.ReadFile(filepath.Join(dir, "stub-delta.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mention := range []string{
+		`.TH "STUB-DELTA" "1"`,
+		`stub\-delta \- subcmd with more subs`,
+		".BI \\-bar \" int\"\nbbb (default 2)",
+		".B india\nsubcmd of a subcmd with more subs",
+		".BR stub (1),",
+		".BR stub\\-delta\\-india (1)\n",
+	} {
+		if !strings.Contains(string(page), mention) {
+			t.Errorf("page does not contain %q\n%s", mention, page)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package alf
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrStaleDocs is returned by CheckMarkdown when the documentation on disk does
+// not match what would be generated.
+var ErrStaleDocs = errors.New("documentation is out of date")
+
+// WriteMarkdown renders reference documentation for the command tree. There is
+// one section per command path, each with a table of its subcommands and a
+// table of its flags.
+//
+// Writing the documentation calls the Setup func of every Command in the tree,
+// see Command.Setup.
+func (r *Root) WriteMarkdown(w io.Writer) error {
+	prog := r.name()
+	bw := bufio.NewWriter(w)
+	err := Walk(r, func(path []string, d Directive, flags *flag.FlagSet) error {
+		writeMarkdownSection(bw, prog, path, d, flags)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// CheckMarkdown compares the output of WriteMarkdown to the contents of the
+// file at filename. If they differ, then the returned error wraps ErrStaleDocs
+// and describes the difference. It's meant for a CI job to detect when the
+// documentation needs to be regenerated.
+func (r *Root) CheckMarkdown(filename string) error {
+	current, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var generated bytes.Buffer
+	if err = r.WriteMarkdown(&generated); err != nil {
+		return err
+	}
+	if bytes.Equal(current, generated.Bytes()) {
+		return nil
+	}
+	diff := lineDiff(filename, "generated", string(current), generated.String())
+	return fmt.Errorf("%w: %s\n%s", ErrStaleDocs, filename, diff)
+}
+
+func writeMarkdownSection(w io.Writer, prog string, path []string, d Directive, flags *flag.FlagSet) {
+	cmd := strings.Join(append([]string{prog}, path...), " ")
+	level := "##"
+	if len(path) < 1 {
+		level = "#"
+	}
+	fmt.Fprintf(w, "%s %s\n\n", level, cmd)
+	if desc := strings.TrimSpace(d.Summary()); desc != "" {
+		fmt.Fprintf(w, "%s\n\n", desc)
+	}
+
+	if del, ok := d.(*Delegator); ok && len(del.Subs) > 0 {
+		fmt.Fprintf(w, "Usage: `%s [flags] subcommand [subflags]`\n\n", cmd)
+		fmt.Fprint(w, "| Subcommand | Description |\n| --- | --- |\n")
+		for _, name := range sortedKeys(del.Subs) {
+			link := fmt.Sprintf("[%s](#%s)", markdownCell(name), markdownAnchor(cmd+" "+name))
+			for _, alias := range del.AliasesOf(name) {
+				link += ", " + markdownCell(alias)
+			}
+			fmt.Fprintf(w, "| %s | %s |\n", link, markdownCell(firstLine(del.Subs[name].Summary())))
+		}
+		fmt.Fprintln(w)
+	} else if c, ok := d.(*Command); ok && c.Args != nil {
+		fmt.Fprintf(w, "Usage: `%s [flags] %s`\n\n", cmd, argsSynopsis(c.Args))
+	} else {
+		fmt.Fprintf(w, "Usage: `%s [flags]`\n\n", cmd)
+	}
+
+	if flags != nil && hasFlags(flags) {
+		fmt.Fprint(w, "| Flag | Type | Default | Description |\n| --- | --- | --- | --- |\n")
+		shorts := shortFlags(flags)
+		flags.VisitAll(func(f *flag.Flag) {
+			if isShortFlag(f) {
+				return
+			}
+			typeName, usage := unquoteUsage(f)
+			def, _ := flagDefault(f)
+			fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n",
+				flagNames(f, shorts[f.Name]), markdownCell(typeName), markdownCode(def), markdownCell(usage))
+		})
+		fmt.Fprintln(w)
+	}
+}
+
+// markdownCell makes s safe to put in a table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "|", `\|`)
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+func markdownCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + markdownCell(s) + "`"
+}
+
+// markdownAnchor approximates how markdown renderers, such as GitHub's, make a
+// link target out of a heading.
+func markdownAnchor(heading string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		case r == ' ':
+			return '-'
+		}
+		return -1
+	}, heading)
+}
+
+// lineDiff describes the changes from a to b, line by line. Removed lines are
+// prefixed with "-", added lines with "+".
+func lineDiff(nameA, nameB, a, b string) string {
+	linesA, linesB := strings.Split(a, "\n"), strings.Split(b, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// linesA[i:] and linesB[j:].
+	lcs := make([][]int, len(linesA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(linesB)+1)
+	}
+	for i := len(linesA) - 1; i >= 0; i-- {
+		for j := len(linesB) - 1; j >= 0; j-- {
+			if linesA[i] == linesB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", nameA, nameB)
+	i, j, inHunk := 0, 0, false
+	for i < len(linesA) || j < len(linesB) {
+		switch {
+		case i < len(linesA) && j < len(linesB) && linesA[i] == linesB[j]:
+			i, j, inHunk = i+1, j+1, false
+			continue
+		case !inHunk:
+			fmt.Fprintf(&out, "@@ line %d @@\n", i+1)
+			inHunk = true
+		}
+		if j >= len(linesB) || i < len(linesA) && lcs[i+1][j] >= lcs[i][j+1] {
+			fmt.Fprintf(&out, "-%s\n", linesA[i])
+			i++
+		} else {
+			fmt.Fprintf(&out, "+%s\n", linesB[j])
+			j++
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,80 @@
+package alf_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+func TestRootWriteMarkdown(t *testing.T) {
+	root := newStubRoot("stub", nil, nil)
+	var buf bytes.Buffer
+	if err := root.WriteMarkdown(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, mention := range []string{
+		"# stub\n",
+		"## stub delta india\n",
+		"| [india](#stub-delta-india) | subcmd of a subcmd with more subs |",
+		"| `-bar` | int | `2` | bbb |",
+		"| `-foo` | string | `\"frank\"` | root flag example |",
+		"| `-quebec` |  | `true` | qqq |",
+	} {
+		if !strings.Contains(out, mention) {
+			t.Errorf("output does not contain %q", mention)
+		}
+	}
+	if strings.Index(out, "## stub alpha") > strings.Index(out, "## stub bravo") {
+		t.Error("sections should be ordered by name")
+	}
+}
+
+func TestRootCheckMarkdown(t *testing.T) {
+	root := newStubRoot("stub", nil, nil)
+	var buf bytes.Buffer
+	if err := root.WriteMarkdown(&buf); err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "cli.md")
+
+	t.Run("ok", func(t *testing.T) {
+		if err := os.WriteFile(filename, buf.Bytes(), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := root.CheckMarkdown(filename); err != nil {
+			t.Errorf("unexpected error; %v", err)
+		}
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		stale := strings.Replace(buf.String(), "root flag example", "old description", 1)
+		if err := os.WriteFile(filename, []byte(stale), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		err := root.CheckMarkdown(filename)
+		if !errors.Is(err, alf.ErrStaleDocs) {
+			t.Fatalf("expected %v, got %v", alf.ErrStaleDocs, err)
+		}
+		for _, mention := range []string{
+			"-| `-foo` | string | `\"frank\"` | old description |",
+			"+| `-foo` | string | `\"frank\"` | root flag example |",
+		} {
+			if !strings.Contains(err.Error(), mention) {
+				t.Errorf("error does not contain %q\n%v", mention, err)
+			}
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		err := root.CheckMarkdown(filepath.Join(t.TempDir(), "nope.md"))
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected %v, got %v", os.ErrNotExist, err)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package alf
+
+import "context"
+
+// A Middleware wraps a Directive to do something before or after it performs,
+// such as logging, timing or checking authorization. It should return a
+// Directive whose Perform method calls next.Perform, unless it means to stop
+// execution. See WrapPerform for a shortcut.
+type Middleware func(next Directive) Directive
+
+// Use adds middleware to the Delegator. The middleware wraps every Perform call
+// that the Delegator makes to a selected subcommand, and that any descendant
+// Delegator makes to its subcommand. So it runs once for each level of the
+// command path below the Delegator. Middleware added first is the outermost,
+// and middleware of an ancestor wraps the middleware of a descendant.
+//
+// Use it on a Root to wrap everything, or on a nested Delegator to scope the
+// middleware to its subtree. Inside the wrapped Perform, CommandPath tells
+// which subcommand was selected, and InvocationFrom tells more.
+func (d *Delegator) Use(mw ...Middleware) {
+	d.middleware = append(d.middleware, mw...)
+}
+
+// WrapPerform makes a Directive with the same Summary as next and a different
+// Perform method. It's meant for writing a Middleware:
+//
+//	func timer(next alf.Directive) alf.Directive {
+//		return alf.WrapPerform(next, func(ctx context.Context) error {
+//			start := time.Now()
+//			defer func() { log.Println(alf.CommandPath(ctx), time.Since(start)) }()
+//			return next.Perform(ctx)
+//		})
+//	}
+func WrapPerform(next Directive, perform func(ctx context.Context) error) Directive {
+	return &wrappedDirective{next: next, perform: perform}
+}
+
+type wrappedDirective struct {
+	next    Directive
+	perform func(ctx context.Context) error
+}
+
+func (w *wrappedDirective) Summary() string                   { return w.next.Summary() }
+func (w *wrappedDirective) Perform(ctx context.Context) error { return w.perform(ctx) }
+
+// CommandPath outputs the names of the subcommands selected so far, not
+// including the program name. Within a Command's Run func, or a Middleware
+// wrapping it, it's the full path to the Command, such as ["bar", "cities"].
+// It's empty at the top level.
+func CommandPath(ctx context.Context) []string {
+	path := frameFrom(ctx).path
+	return append(make([]string, 0, len(path)), path...)
+}
+
+// wrap applies the middleware to d, so that the first one is the outermost.
+func wrap(d Directive, middleware []Middleware) Directive {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		d = middleware[i](d)
+	}
+	return d
+}
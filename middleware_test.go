@@ -0,0 +1,115 @@
+package alf_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+func TestMiddleware(t *testing.T) {
+	var calls []string
+	record := func(label string) alf.Middleware {
+		return func(next alf.Directive) alf.Directive {
+			return alf.WrapPerform(next, func(ctx context.Context) error {
+				path := strings.Join(alf.CommandPath(ctx), " ")
+				calls = append(calls, label+" before "+path)
+				err := next.Perform(ctx)
+				calls = append(calls, label+" after "+path)
+				return err
+			})
+		}
+	}
+
+	newRoot := func() alf.Root {
+		var usage string
+		root := newStubRoot("root", &usage, nil)
+		root.Use(record("first"), record("second"))
+		root.Subs["delta"].(*alf.Delegator).Use(record("delta"))
+		return root
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expErr   error
+		expCalls []string
+	}{
+		{
+			name: "command",
+			args: []string{"alpha"},
+			expCalls: []string{
+				"first before alpha", "second before alpha",
+				"second after alpha", "first after alpha",
+			},
+		},
+		{
+			name:   "error passes through",
+			args:   []string{"bravo"},
+			expErr: errStub,
+			expCalls: []string{
+				"first before bravo", "second before bravo",
+				"second after bravo", "first after bravo",
+			},
+		},
+		{
+			name: "scoped to a Delegator",
+			args: []string{"delta", "echo"},
+			expCalls: []string{
+				"first before delta", "second before delta",
+				"first before delta echo", "second before delta echo", "delta before delta echo",
+				"delta after delta echo", "second after delta echo", "first after delta echo",
+				"second after delta", "first after delta",
+			},
+		},
+		{
+			name:     "not called for help",
+			args:     []string{"delta", "-h"},
+			expErr:   flag.ErrHelp,
+			expCalls: nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calls = nil
+			root := newRoot()
+			err := root.Run(context.Background(), test.args)
+			if test.expErr == nil && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			} else if !errors.Is(err, test.expErr) {
+				t.Fatalf("expected %v, got %v", test.expErr, err)
+			}
+			if !reflect.DeepEqual(calls, test.expCalls) {
+				t.Errorf("wrong calls\ngot      %q\nexpected %q", calls, test.expCalls)
+			}
+		})
+	}
+
+	t.Run("can stop execution", func(t *testing.T) {
+		root := newRoot()
+		var ran bool
+		root.Subs["alpha"].(*alf.Command).Run = func(ctx context.Context) error { ran = true; return nil }
+		errDenied := errors.New("denied")
+		root.Use(func(next alf.Directive) alf.Directive {
+			return alf.WrapPerform(next, func(ctx context.Context) error { return errDenied })
+		})
+		if err := root.Run(context.Background(), []string{"alpha"}); !errors.Is(err, errDenied) {
+			t.Errorf("expected %v, got %v", errDenied, err)
+		}
+		if ran {
+			t.Error("command should not have run")
+		}
+	})
+
+	t.Run("WrapPerform keeps Summary", func(t *testing.T) {
+		cmd := &alf.Command{Description: "ok command"}
+		if got := alf.WrapPerform(cmd, nil).Summary(); got != cmd.Description {
+			t.Errorf("got %q, expected %q", got, cmd.Description)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package alf
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strings"
+)
+
+// DebugEnv is the environment variable that shows the stack trace of a
+// recovered panic when it's set to a non-empty value. See Root.RecoverPanics.
+const DebugEnv = "ALF_DEBUG"
+
+// PanicError is returned by Root.Run when RecoverPanics is on and something
+// panics.
+type PanicError struct {
+	// Path is the program name and the subcommands selected when the panic
+	// happened.
+	Path []string
+	// Value is what was passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in %q: %v", strings.Join(e.Path, " "), e.Value)
+}
+
+// Unwrap outputs the value passed to panic if it's an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// recoverPanic is deferred in Run. It turns a panic into a PanicError, and lets
+// the user know that it's not their fault.
+func (r *Root) recoverPanic(fr *frame, err *error) {
+	val := recover()
+	if val == nil {
+		return
+	}
+	perr := &PanicError{
+		Path:  append([]string{fr.prog}, fr.run.path...),
+		Value: val,
+		Stack: debug.Stack(),
+	}
+
+	stderr := r.stderr()
+	fmt.Fprintf(stderr, "%s: internal error: %v\n", strings.Join(perr.Path, " "), val)
+	if r.Debug || os.Getenv(DebugEnv) != "" {
+		fmt.Fprintf(stderr, "\n%s", perr.Stack)
+	} else {
+		fmt.Fprintf(stderr, "This is a bug. Set %s=1 to see the stack trace.\n", DebugEnv)
+	}
+	*err = perr
+}
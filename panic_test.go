@@ -0,0 +1,135 @@
+package alf_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+func TestRecoverPanics(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	newRoot := func() *alf.Root {
+		root := newStubRoot("root", new(string), nil)
+		delta := root.Subs["delta"].(*alf.Delegator)
+		delta.Subs["panics"] = &alf.Command{
+			Description: "panics in Run",
+			Setup:       func(p flag.FlagSet) *flag.FlagSet { return newMutedFlagSet("panics", flag.ContinueOnError) },
+			Run:         func(ctx context.Context) error { panic(errBoom) },
+		}
+		delta.Subs["setup"] = &alf.Command{
+			Description: "panics in Setup",
+			Setup:       func(p flag.FlagSet) *flag.FlagSet { panic("bad setup") },
+			Run:         func(ctx context.Context) error { return nil },
+		}
+		root.RecoverPanics = true
+		return &root
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		prePerform func(ctx context.Context) error
+		expPath    []string
+		expValue   any
+	}{
+		{
+			name:     "Run",
+			args:     []string{"delta", "panics"},
+			expPath:  []string{"root", "delta", "panics"},
+			expValue: errBoom,
+		},
+		{
+			name:     "Setup",
+			args:     []string{"delta", "setup"},
+			expPath:  []string{"root", "delta", "setup"},
+			expValue: "bad setup",
+		},
+		{
+			name:       "PrePerform",
+			args:       []string{"alpha"},
+			prePerform: func(ctx context.Context) error { panic(42) },
+			expPath:    []string{"root"},
+			expValue:   42,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(alf.DebugEnv, "")
+			root := newRoot()
+			root.PrePerform = test.prePerform
+
+			var err error
+			stderr := captureFile(t, &os.Stderr, func() { err = root.Run(context.Background(), test.args) })
+
+			var perr *alf.PanicError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected a %T, got %v", perr, err)
+			}
+			if !reflect.DeepEqual(perr.Path, test.expPath) {
+				t.Errorf("wrong Path; got %q, expected %q", perr.Path, test.expPath)
+			}
+			if perr.Value != test.expValue {
+				t.Errorf("wrong Value; got %v, expected %v", perr.Value, test.expValue)
+			}
+			if !strings.Contains(string(perr.Stack), "panic_test.go") {
+				t.Errorf("Stack does not mention where the panic happened\n%s", perr.Stack)
+			}
+
+			expMessage := strings.Join(test.expPath, " ") + ": internal error: "
+			if !strings.Contains(stderr, expMessage) {
+				t.Errorf("stderr does not contain %q\n%s", expMessage, stderr)
+			}
+			if strings.Contains(stderr, "goroutine") {
+				t.Errorf("stack trace should not be shown\n%s", stderr)
+			}
+		})
+	}
+
+	t.Run("Unwrap", func(t *testing.T) {
+		var err error
+		captureFile(t, &os.Stderr, func() { err = newRoot().Run(context.Background(), []string{"delta", "panics"}) })
+		if !errors.Is(err, errBoom) {
+			t.Errorf("expected %v to wrap %v", err, errBoom)
+		}
+	})
+
+	t.Run("Debug", func(t *testing.T) {
+		for _, debug := range []struct {
+			name  string
+			field bool
+			env   string
+		}{
+			{name: "field", field: true},
+			{name: "env", env: "1"},
+		} {
+			t.Run(debug.name, func(t *testing.T) {
+				t.Setenv(alf.DebugEnv, debug.env)
+				root := newRoot()
+				root.Debug = debug.field
+				stderr := captureFile(t, &os.Stderr, func() { root.Run(context.Background(), []string{"delta", "panics"}) })
+				if !strings.Contains(stderr, "goroutine") {
+					t.Errorf("stack trace should be shown\n%s", stderr)
+				}
+			})
+		}
+	})
+
+	t.Run("off by default", func(t *testing.T) {
+		root := newRoot()
+		root.RecoverPanics = false
+		defer func() {
+			if recover() == nil {
+				t.Error("expected a panic")
+			}
+		}()
+		root.Run(context.Background(), []string{"delta", "panics"})
+	})
+}
@@ -0,0 +1,94 @@
+package alf
+
+import "flag"
+
+// parse sets up and parses the flags of the Directive at this frame's level in
+// the command tree. Afterwards, the flags are noted in the frame so that
+// descendants can tell which flags they inherited.
+func (fr *frame) parse(d Directive, flags *flag.FlagSet, args []string) (err error) {
+	if del, ok := d.(*Delegator); ok {
+		fr.persistent = appendPersistent(fr.persistent, del)
+	}
+	definePersistent(flags, fr.persistent)
+	if fr.root != nil && fr.root.Err != nil && fr.run != nil {
+		fr.run.setOutput(flags, fr.root.Err)
+	}
+	fr.flags = append(fr.flags[:len(fr.flags):len(fr.flags)], flags)
+	setDefaultUsage(fr, d, flags)
+	_, isCommand := d.(*Command)
+	args = expandShortFlags(flags, args, isCommand && fr.interspersed)
+	if isCommand && fr.interspersed {
+		err = parseInterspersed(flags, args)
+	} else {
+		err = flags.Parse(args)
+	}
+	if err != nil {
+		// The flag package calls the Usage func when parsing fails.
+		fr.traceUsageShown()
+	}
+	if err == flag.ErrHelp {
+		return
+	} else if err != nil {
+		return &parseError{err: err}
+	}
+	if err = applyEnv(fr, flags); err != nil {
+		return
+	}
+	if err = applyConfig(fr, flags); err != nil {
+		return
+	}
+	if isCommand {
+		if err = fr.applyPersistent(); err != nil {
+			return
+		}
+	}
+
+	// Take note of the flags now. A flag set made by copying its parent, like
+	// in a Command's Setup func, shares its parent's map of flags. Looking up a
+	// name in the parent flag set later on would also find the child's flags.
+	fr.ownFlags = nil
+	flags.VisitAll(func(f *flag.Flag) { fr.ownFlags = append(fr.ownFlags, f) })
+	return
+}
+
+// parseInterspersed parses flags from anywhere in args, until a "--". Afterwards,
+// the positional arguments are in flags.Args as usual.
+func parseInterspersed(flags *flag.FlagSet, args []string) error {
+	var positionals []string
+	for len(args) > 0 {
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		rest := flags.Args()
+		// Parse stops at the first positional argument, or right after "--".
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positionals = append(positionals, rest...)
+			break
+		} else if len(rest) < 1 {
+			break
+		}
+		positionals = append(positionals, rest[0])
+		args = rest[1:]
+	}
+	return flags.Parse(append([]string{"--"}, positionals...))
+}
+
+// parseError is returned when the command line flags are invalid. By then, the
+// flag set has already output the error message and its usage.
+type parseError struct{ err error }
+
+func (e *parseError) Error() string { return e.err.Error() }
+func (e *parseError) Unwrap() error { return e.err }
+
+// inherited reports whether f is defined in the flag set of an ancestor. This
+// happens when a Command's Setup func reuses its parent's flags. A flag that
+// only has the same name as an ancestor's flag, but its own value, is not
+// inherited.
+func (fr *frame) inherited(f *flag.Flag) bool {
+	for _, a := range fr.ancestorFlags {
+		if a.Name == f.Name && sameValue(a.Value, f.Value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package alf_test
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+func TestInterspersed(t *testing.T) {
+	type result struct {
+		alpha       int
+		bravo       bool
+		charlie     string
+		positionals []string
+	}
+
+	newRoot := func(delegator, command bool, got *result) *alf.Root {
+		var cityFlags *flag.FlagSet
+		bar := &alf.Delegator{
+			Description: "bar",
+			Flags:       newMutedFlagSet("bar", flag.ContinueOnError),
+			Subs: map[string]alf.Directive{
+				"cities": &alf.Command{
+					Description: "takes positional args",
+					Setup: func(p flag.FlagSet) *flag.FlagSet {
+						cityFlags = newMutedFlagSet("cities", flag.ContinueOnError)
+						cityFlags.BoolVar(&got.bravo, "bravo", false, "bbb")
+						cityFlags.StringVar(&got.charlie, "charlie", "", "ccc")
+						return cityFlags
+					},
+					Run: func(ctx context.Context) error {
+						got.positionals = cityFlags.Args()
+						return nil
+					},
+					Interspersed: command,
+				},
+			},
+			Interspersed: delegator,
+		}
+		bar.Flags.IntVar(&got.alpha, "alpha", 0, "aaa")
+		return &alf.Root{
+			Delegator: &alf.Delegator{
+				Description: "root",
+				Flags:       newMutedFlagSet("root", flag.ContinueOnError),
+				Subs:        map[string]alf.Directive{"bar": bar},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		delegator bool
+		command   bool
+		args      []string
+		exp       result
+		expErr    bool
+	}{
+		{
+			name: "off",
+			args: []string{"bar", "cities", "paris", "-bravo"},
+			exp:  result{positionals: []string{"paris", "-bravo"}},
+		},
+		{
+			name:    "Command",
+			command: true,
+			args:    []string{"bar", "cities", "paris", "-bravo"},
+			exp:     result{bravo: true, positionals: []string{"paris"}},
+		},
+		{
+			name:    "flags between positionals",
+			command: true,
+			args:    []string{"bar", "cities", "paris", "-charlie", "chan", "rome", "-bravo", "oslo"},
+			exp:     result{bravo: true, charlie: "chan", positionals: []string{"paris", "rome", "oslo"}},
+		},
+		{
+			name:    "terminator",
+			command: true,
+			args:    []string{"bar", "cities", "paris", "--", "-bravo", "--"},
+			exp:     result{positionals: []string{"paris", "-bravo", "--"}},
+		},
+		{
+			name:    "no positionals",
+			command: true,
+			args:    []string{"bar", "cities", "-bravo"},
+			exp:     result{bravo: true},
+		},
+		{
+			name:    "unknown flag",
+			command: true,
+			args:    []string{"bar", "cities", "paris", "-delta"},
+			expErr:  true,
+		},
+		{
+			name:      "inherited from Delegator",
+			delegator: true,
+			args:      []string{"bar", "-alpha", "2", "cities", "paris", "-bravo"},
+			exp:       result{alpha: 2, bravo: true, positionals: []string{"paris"}},
+		},
+		{
+			name:      "Delegator flags stop at the subcommand",
+			delegator: true,
+			args:      []string{"bar", "cities", "-alpha", "2"},
+			expErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got result
+			err := newRoot(test.delegator, test.command, &got).Run(context.Background(), test.args)
+			if err != nil && !test.expErr {
+				t.Fatalf("unexpected error %v", err)
+			} else if err == nil && test.expErr {
+				t.Fatal("expected error, got none")
+			}
+			if test.expErr {
+				return
+			}
+			if len(got.positionals) == 0 {
+				got.positionals = nil
+			}
+			if !reflect.DeepEqual(got, test.exp) {
+				t.Errorf("got %+v, expected %+v", got, test.exp)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package alf
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+)
+
+// appendPersistent adds the persistent flags of a Delegator to the ones from its
+// ancestors. The input slice is not modified.
+func appendPersistent(persistent []*flag.Flag, d *Delegator) []*flag.Flag {
+	if d.PersistentFlags == nil {
+		return persistent
+	}
+	out := persistent[:len(persistent):len(persistent)]
+	d.PersistentFlags.VisitAll(func(f *flag.Flag) { out = append(out, f) })
+	return out
+}
+
+// isPersistent reports whether f is one of the persistent flags collected so
+// far, as opposed to a flag of the same name defined at this level.
+func (fr *frame) isPersistent(f *flag.Flag) bool {
+	for _, p := range fr.persistent {
+		if p.Name == f.Name && sameValue(p.Value, f.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// definePersistent adds the persistent flags to flags. The flags share their
+// values with the persistent flags, so they're all bound to the same variable.
+// A flag already defined in flags takes precedence.
+func definePersistent(flags *flag.FlagSet, persistent []*flag.Flag) {
+	for _, p := range persistent {
+		if flags.Lookup(p.Name) != nil {
+			continue
+		}
+		flags.Var(p.Value, p.Name, p.Usage)
+		flags.Lookup(p.Name).DefValue = p.DefValue
+	}
+}
+
+// applyPersistent gives the persistent flags their values from the environment
+// and the Config, once the whole path is parsed. Doing it at the level that
+// defines a flag would come before the command line of the levels below it,
+// and a flag like StringsVar would collect both. A flag that was set at any
+// level is skipped. Otherwise, it's set in the flag set of the level that
+// defines it, with the environment variable and Config section of that level.
+func (fr *frame) applyPersistent() error {
+	var prefix string
+	if fr.root != nil {
+		prefix = fr.root.EnvPrefix
+	}
+	set := fr.setValues()
+	for _, p := range fr.persistent {
+		if set.has(p.Value) {
+			continue
+		}
+		level := -1
+		for i, flags := range fr.flags {
+			if f := flags.Lookup(p.Name); f != nil && sameValue(f.Value, p.Value) {
+				level = i
+				break
+			}
+		}
+		if level < 0 {
+			continue
+		}
+		flags, path := fr.flags[level], fr.path[:level]
+		if name := envVar(prefix, path, p); name != "" {
+			if ok, err := setFromEnv(flags, envBinding{flag: p, name: name}); err != nil {
+				return err
+			} else if ok {
+				continue
+			}
+		}
+		section := strings.Join(path, ".")
+		if err := setFromConfig(flags, section, p.Name, fr.config[section][p.Name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withPersistent is like definePersistent, except that it doesn't modify flags.
+// If anything needs to be added, then it's added to a clone.
+func withPersistent(flags *flag.FlagSet, persistent []*flag.Flag) *flag.FlagSet {
+	for _, p := range persistent {
+		if flags.Lookup(p.Name) == nil {
+			flags = cloneFlagSet(flags)
+			definePersistent(flags, persistent)
+			break
+		}
+	}
+	return flags
+}
+
+// sameValue reports whether a and b are the same flag value. Values are usually
+// pointers, but they don't have to be comparable.
+func sameValue(a, b flag.Value) bool {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	return ta == tb && ta.Comparable() && a == b
+}
@@ -0,0 +1,212 @@
+package alf_test
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+type persistentTestArgs struct {
+	verbose bool
+	profile string
+	echo    bool
+	shadow  string
+}
+
+func newPersistentTestRoot(out *bytes.Buffer, args *persistentTestArgs) *alf.Root {
+	newFlags := func(name string) *flag.FlagSet {
+		flags := flag.NewFlagSet(name, flag.ContinueOnError)
+		flags.SetOutput(out)
+		return flags
+	}
+	run := func(ctx context.Context) error { return nil }
+
+	delta := &alf.Delegator{
+		Description:     "has persistent flags",
+		Flags:           newFlags("delta"),
+		PersistentFlags: newFlags("delta"),
+		Subs: map[string]alf.Directive{
+			"echo": &alf.Command{
+				Description: "has its own flags",
+				Setup: func(p flag.FlagSet) *flag.FlagSet {
+					f := newFlags("echo")
+					f.BoolVar(&args.echo, "e", false, "eee")
+					return f
+				},
+				Run: run,
+			},
+			"shadow": &alf.Command{
+				Description: "defines a flag named like a persistent flag",
+				Setup: func(p flag.FlagSet) *flag.FlagSet {
+					f := newFlags("shadow")
+					f.StringVar(&args.shadow, "profile", "", "not the persistent one")
+					return f
+				},
+				Run: run,
+			},
+		},
+	}
+	delta.PersistentFlags.StringVar(&args.profile, "profile", "default", "which profile to use")
+
+	root := &alf.Root{
+		Delegator: &alf.Delegator{
+			Description:     "root",
+			Flags:           newFlags("stub"),
+			PersistentFlags: newFlags("stub"),
+			Subs: map[string]alf.Directive{
+				"alpha": &alf.Command{
+					Description: "reuses parent flags",
+					Setup:       func(p flag.FlagSet) *flag.FlagSet { return &p },
+					Run:         run,
+				},
+				"delta": delta,
+			},
+		},
+	}
+	root.PersistentFlags.BoolVar(&args.verbose, "v", false, "verbose output")
+	return root
+}
+
+func TestPersistentFlags(t *testing.T) {
+	tests := []struct {
+		args   []string
+		exp    persistentTestArgs
+		expErr bool
+	}{
+		{args: []string{"alpha"}, exp: persistentTestArgs{profile: "default"}},
+		{args: []string{"-v", "alpha"}, exp: persistentTestArgs{verbose: true, profile: "default"}},
+		{args: []string{"alpha", "-v"}, exp: persistentTestArgs{verbose: true, profile: "default"}},
+		{args: []string{"delta", "-v", "echo"}, exp: persistentTestArgs{verbose: true, profile: "default"}},
+		{args: []string{"delta", "echo", "-v", "-e"}, exp: persistentTestArgs{verbose: true, profile: "default", echo: true}},
+		{args: []string{"delta", "-profile", "p", "echo"}, exp: persistentTestArgs{profile: "p"}},
+		{args: []string{"delta", "echo", "-profile", "p"}, exp: persistentTestArgs{profile: "p"}},
+		{args: []string{"delta", "shadow", "-profile", "p"}, exp: persistentTestArgs{profile: "default", shadow: "p"}},
+		{args: []string{"alpha", "-profile", "p"}, exp: persistentTestArgs{profile: "default"}, expErr: true},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		var got persistentTestArgs
+		err := newPersistentTestRoot(&out, &got).Run(context.Background(), test.args)
+		if err != nil && !test.expErr {
+			t.Errorf("args %q; unexpected error %v", test.args, err)
+		} else if err == nil && test.expErr {
+			t.Errorf("args %q; expected error, got none", test.args)
+		}
+		if got != test.exp {
+			t.Errorf("args %q; got %+v, expected %+v", test.args, got, test.exp)
+		}
+	}
+
+	t.Run("help", func(t *testing.T) {
+		var out bytes.Buffer
+		root := newPersistentTestRoot(&out, &persistentTestArgs{})
+		root.Run(context.Background(), []string{"delta", "echo", "-h"})
+		help := out.String()
+
+		flags, global, ok := strings.Cut(help, "Global flags:")
+		if !ok {
+			t.Fatalf("help does not list global flags\n%s", help)
+		}
+		if !strings.Contains(flags, "-e\teee") {
+			t.Errorf("own flags should be listed under Flags\n%s", help)
+		}
+		for _, mention := range []string{"-v\tverbose output", "-profile string"} {
+			if strings.Contains(flags, mention) {
+				t.Errorf("%q should not be listed under Flags\n%s", mention, help)
+			}
+			if !strings.Contains(global, mention) {
+				t.Errorf("%q should be listed under Global flags\n%s", mention, help)
+			}
+		}
+	})
+
+	t.Run("Walk", func(t *testing.T) {
+		root := newPersistentTestRoot(&bytes.Buffer{}, &persistentTestArgs{})
+		exp := map[string][]string{
+			"":             {"v"},
+			"alpha":        {"v"},
+			"delta":        {"profile", "v"},
+			"delta echo":   {"e", "profile", "v"},
+			"delta shadow": {"profile", "v"},
+		}
+		err := alf.Walk(root, func(path []string, d alf.Directive, flags *flag.FlagSet) error {
+			var names []string
+			flags.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+			key := strings.Join(path, " ")
+			if strings.Join(names, " ") != strings.Join(exp[key], " ") {
+				t.Errorf("path %q; got flags %q, expected %q", key, names, exp[key])
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root.Flags.Lookup("v") != nil {
+			t.Error("Walk should not modify the flag sets")
+		}
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		root := newPersistentTestRoot(&bytes.Buffer{}, &persistentTestArgs{})
+		out := captureStdout(t, func() {
+			root.Run(context.Background(), []string{"__complete", "delta", "echo", "-"})
+		})
+		if got, exp := strings.Fields(out), "-e -profile -v"; strings.Join(got, " ") != exp {
+			t.Errorf("got %q, expected %q", got, exp)
+		}
+	})
+	t.Run("env and config", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			args   []string
+			env    string
+			config []string
+			exp    []string
+		}{
+			{name: "env", args: []string{"cmd"}, env: "fromenv", exp: []string{"fromenv"}},
+			{name: "config", args: []string{"cmd"}, config: []string{"fromconfig"}, exp: []string{"fromconfig"}},
+			{name: "env over config", args: []string{"cmd"}, env: "fromenv", config: []string{"fromconfig"}, exp: []string{"fromenv"}},
+			{name: "command line after the subcommand", args: []string{"cmd", "-tag", "cli"}, env: "fromenv", config: []string{"fromconfig"}, exp: []string{"cli"}},
+			{name: "command line before the subcommand", args: []string{"-tag", "cli", "cmd"}, env: "fromenv", config: []string{"fromconfig"}, exp: []string{"cli"}},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				t.Setenv("PR_TAG", test.env)
+				var tags []string
+				root := &alf.Root{
+					Delegator: &alf.Delegator{
+						Description:     "root",
+						Flags:           newMutedFlagSet("stub", flag.ContinueOnError),
+						PersistentFlags: newMutedFlagSet("stub", flag.ContinueOnError),
+						Subs: map[string]alf.Directive{
+							"cmd": &alf.Command{
+								Description: "has no flags of its own",
+								Setup: func(p flag.FlagSet) *flag.FlagSet {
+									return newMutedFlagSet("cmd", flag.ContinueOnError)
+								},
+								Run: func(ctx context.Context) error { return nil },
+							},
+						},
+					},
+					EnvPrefix: "PR",
+					LoadConfig: func(ctx context.Context) (alf.Config, error) {
+						return alf.Config{"": {"tag": test.config}}, nil
+					},
+				}
+				alf.StringsVar(root.PersistentFlags, &tags, "tag", nil, "repeatable")
+				if err := root.Run(context.Background(), test.args); err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(tags, test.exp) {
+					t.Errorf("wrong tags; got %q, expected %q", tags, test.exp)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package alf
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ShortFlag defines a one-letter short form of an existing flag. The short form
+// shares the value of the long one. For example, after
+//
+//	flags.BoolVar(&verbose, "verbose", false, "show more output")
+//	alf.ShortFlag(flags, "v", "verbose")
+//
+// both -v and --verbose set verbose. The flag package already accepts one or
+// two dashes for any flag. Short boolean flags can be clustered, so "-xvf" is
+// the same as "-x -v -f". The last flag of a cluster may take a value, which is
+// either the rest of the cluster or the next argument. Help messages show the
+// pair together, like "-v, --verbose".
+//
+// It panics if short is not a single character or if long is not defined, as
+// the flag package does for similar mistakes.
+func ShortFlag(flags *flag.FlagSet, short, long string) {
+	if utf8.RuneCountInString(short) != 1 {
+		panic(fmt.Sprintf("short flag %q should be a single character", short))
+	}
+	target := flags.Lookup(long)
+	if target == nil {
+		panic(fmt.Sprintf("short flag %q refers to undefined flag %q", short, long))
+	}
+	flags.Var(&shortValue{Value: target.Value, long: long}, short, target.Usage)
+	flags.Lookup(short).DefValue = target.DefValue
+}
+
+// shortValue marks a flag as the short form of another.
+type shortValue struct {
+	flag.Value
+	long string
+}
+
+func (v *shortValue) IsBoolFlag() bool {
+	b, ok := v.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func (v *shortValue) Complete(ctx context.Context, args []string, toComplete string) []string {
+	if c, ok := v.Value.(Completer); ok {
+		return c.Complete(ctx, args, toComplete)
+	}
+	return nil
+}
+
+// isShortFlag reports whether f was defined by ShortFlag.
+func isShortFlag(f *flag.Flag) bool {
+	_, ok := f.Value.(*shortValue)
+	return ok
+}
+
+// shortFlags maps the name of each long flag to its short form.
+func shortFlags(flags *flag.FlagSet) map[string]string {
+	out := make(map[string]string)
+	flags.VisitAll(func(f *flag.Flag) {
+		if short, ok := f.Value.(*shortValue); ok {
+			out[short.long] = f.Name
+		}
+	})
+	return out
+}
+
+// setFlags has the names of the flags that have been set. A flag set by its
+// short form counts as set by its long name too.
+func setFlags(flags *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for long, short := range shortFlags(flags) {
+		set[long] = set[long] || set[short]
+	}
+	return set
+}
+
+// setAlongPath is like setFlags, except that a flag also counts as set if it
+// was set in the flag set of another level, as long as it's the same flag. This
+// happens with a persistent flag specified before the subcommand, or given a
+// value from the environment or a Config at the level that defines it.
+func (fr *frame) setAlongPath(flags *flag.FlagSet) map[string]bool {
+	set := setFlags(flags)
+	values := fr.setValues()
+	flags.VisitAll(func(f *flag.Flag) {
+		if values.has(f.Value) {
+			set[f.Name] = true
+		}
+	})
+	return set
+}
+
+// flagValues is a list of flag values, compared with sameValue.
+type flagValues []flag.Value
+
+func (vs flagValues) has(v flag.Value) bool {
+	for _, other := range vs {
+		if sameValue(v, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// setValues has the values of the flags set at any level parsed so far.
+func (fr *frame) setValues() flagValues {
+	var out flagValues
+	for _, level := range fr.flags {
+		for name, ok := range setFlags(level) {
+			if f := level.Lookup(name); ok && f != nil {
+				out = append(out, f.Value)
+			}
+		}
+	}
+	return out
+}
+
+// flagNames outputs the name of the flag for documentation, along with its
+// short form if there is one, such as "-v, --verbose".
+func flagNames(f *flag.Flag, short string) string {
+	if short == "" {
+		return "-" + f.Name
+	}
+	return "-" + short + ", --" + f.Name
+}
+
+// expandShortFlags splits up clusters of short flags, so the flag package can
+// parse them. It looks at args the way the flag package would, so positional
+// arguments are left alone. If interspersed is false, then nothing after the
+// first positional argument is changed.
+func expandShortFlags(flags *flag.FlagSet, args []string, interspersed bool) []string {
+	if len(shortFlags(flags)) < 1 {
+		return args
+	}
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(out, args[i:]...)
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			if !interspersed {
+				return append(out, args[i:]...)
+			}
+			out = append(out, arg)
+			continue
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			out = append(out, arg)
+			continue
+		}
+		if f := flags.Lookup(name); f != nil {
+			out = append(out, arg)
+			if !isBoolFlag(f) && i+1 < len(args) {
+				// The next arg is the value.
+				i++
+				out = append(out, args[i])
+			}
+			continue
+		}
+		cluster, ok := splitCluster(flags, arg)
+		if !ok {
+			out = append(out, arg) // let the flag package report the error
+			continue
+		}
+		out = append(out, cluster...)
+		if last := flags.Lookup(strings.TrimPrefix(cluster[len(cluster)-1], "-")); last != nil && !isBoolFlag(last) && i+1 < len(args) {
+			i++
+			out = append(out, args[i])
+		}
+	}
+	return out
+}
+
+// splitCluster turns an arg like "-xvf" into "-x", "-v", "-f". Each letter must
+// be a short flag. A short flag that takes a value ends the cluster, the rest
+// of the arg is its value, as in "-ffile".
+func splitCluster(flags *flag.FlagSet, arg string) ([]string, bool) {
+	if strings.HasPrefix(arg, "--") {
+		return nil, false
+	}
+	var out []string
+	letters := arg[1:]
+	for i, r := range letters {
+		f := flags.Lookup(string(r))
+		if f == nil || !isShortFlag(f) {
+			return nil, false
+		}
+		rest := letters[i+utf8.RuneLen(r):]
+		if !isBoolFlag(f) && rest != "" {
+			return append(out, "-"+f.Name+"="+rest), true
+		}
+		out = append(out, "-"+f.Name)
+	}
+	return out, true
+}
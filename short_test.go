@@ -0,0 +1,128 @@
+package alf_test
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+func TestShortFlag(t *testing.T) {
+	type result struct {
+		verbose, extract bool
+		file             string
+		positionals      []string
+	}
+
+	newRoot := func(out *bytes.Buffer, interspersed bool, got *result) *alf.Root {
+		var flags *flag.FlagSet
+		return &alf.Root{
+			Delegator: &alf.Delegator{
+				Description: "root",
+				Flags:       newMutedFlagSet("root", flag.ContinueOnError),
+				Subs: map[string]alf.Directive{
+					"tar": &alf.Command{
+						Description: "has short flags",
+						Setup: func(p flag.FlagSet) *flag.FlagSet {
+							flags = flag.NewFlagSet("tar", flag.ContinueOnError)
+							flags.SetOutput(out)
+							flags.BoolVar(&got.verbose, "verbose", false, "show more output")
+							flags.BoolVar(&got.extract, "extract", false, "extract files")
+							flags.StringVar(&got.file, "file", "", "use archive `path`")
+							alf.ShortFlag(flags, "v", "verbose")
+							alf.ShortFlag(flags, "x", "extract")
+							alf.ShortFlag(flags, "f", "file")
+							return flags
+						},
+						Run: func(ctx context.Context) error {
+							got.positionals = flags.Args()
+							return nil
+						},
+						Interspersed: interspersed,
+					},
+				},
+			},
+			EnvPrefix: "STUB",
+		}
+	}
+
+	tests := []struct {
+		name         string
+		interspersed bool
+		args         []string
+		exp          result
+		expErr       bool
+	}{
+		{name: "short", args: []string{"-v"}, exp: result{verbose: true}},
+		{name: "long", args: []string{"--verbose", "-extract"}, exp: result{verbose: true, extract: true}},
+		{name: "cluster", args: []string{"-xv"}, exp: result{verbose: true, extract: true}},
+		{name: "cluster with value", args: []string{"-xvf", "a.tar", "b"}, exp: result{verbose: true, extract: true, file: "a.tar", positionals: []string{"b"}}},
+		{name: "cluster with attached value", args: []string{"-xfa.tar", "b"}, exp: result{extract: true, file: "a.tar", positionals: []string{"b"}}},
+		{name: "value after a flag", args: []string{"-f", "-xv"}, exp: result{file: "-xv"}},
+		{name: "positionals left alone", args: []string{"-v", "b", "-xv"}, exp: result{verbose: true, positionals: []string{"b", "-xv"}}},
+		{name: "terminator", args: []string{"-v", "--", "-xv"}, exp: result{verbose: true, positionals: []string{"-xv"}}},
+		{name: "interspersed", interspersed: true, args: []string{"b", "-xv", "c"}, exp: result{verbose: true, extract: true, positionals: []string{"b", "c"}}},
+		{name: "unknown in cluster", args: []string{"-vq"}, expErr: true},
+		{name: "long names don't cluster", args: []string{"--xv"}, expErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got result
+			err := newRoot(&bytes.Buffer{}, test.interspersed, &got).Run(context.Background(), append([]string{"tar"}, test.args...))
+			if err != nil && !test.expErr {
+				t.Fatalf("unexpected error %v", err)
+			} else if err == nil && test.expErr {
+				t.Fatal("expected error, got none")
+			}
+			if test.expErr {
+				return
+			}
+			if len(got.positionals) == 0 {
+				got.positionals = nil
+			}
+			if !reflect.DeepEqual(got, test.exp) {
+				t.Errorf("got %+v, expected %+v", got, test.exp)
+			}
+		})
+	}
+
+	t.Run("help", func(t *testing.T) {
+		var out bytes.Buffer
+		newRoot(&out, false, &result{}).Run(context.Background(), []string{"tar", "-h"})
+		help := out.String()
+		for _, mention := range []string{"  -f, --file path\n", "  -v, --verbose\n", "  -x, --extract\n", "\tSTUB_TAR_VERBOSE"} {
+			if !strings.Contains(help, mention) {
+				t.Errorf("help does not contain %q\n%s", mention, help)
+			}
+		}
+		for _, mention := range []string{"  -v\t", "STUB_TAR_V\t", "STUB_TAR_V "} {
+			if strings.Contains(help, mention) {
+				t.Errorf("help should not contain %q\n%s", mention, help)
+			}
+		}
+	})
+
+	t.Run("panics", func(t *testing.T) {
+		for _, test := range []struct{ short, long string }{
+			{"vv", "verbose"},
+			{"", "verbose"},
+			{"q", "quiet"},
+		} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("ShortFlag(%q, %q) should panic", test.short, test.long)
+					}
+				}()
+				flags := flag.NewFlagSet("", flag.ContinueOnError)
+				flags.Bool("verbose", false, "")
+				alf.ShortFlag(flags, test.short, test.long)
+			}()
+		}
+	})
+}
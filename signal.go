@@ -0,0 +1,98 @@
+package alf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// osExit is os.Exit, except in tests.
+var osExit = os.Exit
+
+// SignalError is returned by Root.Run when one of the Root's Signals
+// interrupted the command.
+type SignalError struct {
+	// Signal is the first signal received.
+	Signal os.Signal
+	// Err is what the command returned after it was interrupted. It's often
+	// context.Canceled, or nil if the command ignored the context.
+	Err error
+}
+
+func (e *SignalError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("interrupted by signal: %v", e.Signal)
+	}
+	return fmt.Sprintf("interrupted by signal: %v: %v", e.Signal, e.Err)
+}
+
+func (e *SignalError) Unwrap() error { return e.Err }
+
+// ExitCode follows the shell convention of 128 plus the signal number, so an
+// interrupt (SIGINT) is 130 and SIGTERM is 143.
+func (e *SignalError) ExitCode() int { return signalExitCode(e.Signal) }
+
+func signalExitCode(sig os.Signal) int {
+	if num, ok := sig.(syscall.Signal); ok {
+		return 128 + int(num)
+	}
+	return 1
+}
+
+// notifySignals derives a context that's canceled when one of the Root's
+// Signals is received. Once that happens, another signal or the expiry of the
+// GracePeriod exits the program right away. Call stop when the command is done,
+// it outputs the signal that canceled the context, if any.
+func (r *Root) notifySignals(ctx context.Context) (out context.Context, stop func() os.Signal) {
+	out, cancel := context.WithCancel(ctx)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, r.Signals...)
+
+	var (
+		mu       sync.Mutex
+		received os.Signal
+		done     = make(chan struct{})
+	)
+	go func() {
+		var first os.Signal
+		select {
+		case first = <-signals:
+			mu.Lock()
+			received = first
+			mu.Unlock()
+			cancel()
+		case <-done:
+			return
+		}
+
+		var timeout <-chan time.Time
+		if r.GracePeriod > 0 {
+			timer := time.NewTimer(r.GracePeriod)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		select {
+		case sig := <-signals:
+			fmt.Fprintf(r.stderr(), "received %v again, exiting now\n", sig)
+			osExit(signalExitCode(sig))
+		case <-timeout:
+			fmt.Fprintf(r.stderr(), "did not stop within %v of %v, exiting now\n", r.GracePeriod, first)
+			osExit(signalExitCode(first))
+		case <-done:
+		}
+	}()
+
+	stop = func() os.Signal {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+		mu.Lock()
+		defer mu.Unlock()
+		return received
+	}
+	return
+}
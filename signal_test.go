@@ -0,0 +1,107 @@
+//go:build unix
+
+package alf_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+func TestRootSignals(t *testing.T) {
+	// newRoot makes a Root with a command that does whatever run says, while
+	// listening for SIGUSR1 so the tests don't interrupt anything else.
+	newRoot := func(run func(ctx context.Context) error) *alf.Root {
+		root := newStubRoot("root", new(string), nil)
+		root.Subs["sleep"] = &alf.Command{
+			Description: "waits",
+			Setup:       func(p flag.FlagSet) *flag.FlagSet { return newMutedFlagSet("sleep", flag.ContinueOnError) },
+			Run:         run,
+		}
+		root.Signals = []os.Signal{syscall.SIGUSR1}
+		return &root
+	}
+	interrupt := func(t *testing.T) {
+		t.Helper()
+		if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mockExit := func(t *testing.T) <-chan int {
+		codes := make(chan int, 1)
+		t.Cleanup(alf.SetOsExit(func(code int) { codes <- code }))
+		return codes
+	}
+
+	t.Run("not interrupted", func(t *testing.T) {
+		root := newRoot(func(ctx context.Context) error { return errStub })
+		err := root.Run(context.Background(), []string{"sleep"})
+		if err != errStub {
+			t.Errorf("expected %v, got %v", errStub, err)
+		}
+	})
+
+	t.Run("interrupted", func(t *testing.T) {
+		root := newRoot(func(ctx context.Context) error {
+			interrupt(t)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		err := root.Run(context.Background(), []string{"sleep"})
+		var serr *alf.SignalError
+		if !errors.As(err, &serr) {
+			t.Fatalf("expected a %T, got %v", serr, err)
+		}
+		if serr.Signal != syscall.SIGUSR1 {
+			t.Errorf("wrong Signal; got %v, expected %v", serr.Signal, syscall.SIGUSR1)
+		}
+		if exp := 128 + int(syscall.SIGUSR1); serr.ExitCode() != exp {
+			t.Errorf("wrong ExitCode; got %d, expected %d", serr.ExitCode(), exp)
+		}
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v to wrap %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("second signal", func(t *testing.T) {
+		codes := mockExit(t)
+		var code int
+		root := newRoot(func(ctx context.Context) error {
+			interrupt(t)
+			<-ctx.Done()
+			interrupt(t)
+			code = <-codes
+			return nil
+		})
+		captureFile(t, &os.Stderr, func() { root.Run(context.Background(), []string{"sleep"}) })
+		if exp := 128 + int(syscall.SIGUSR1); code != exp {
+			t.Errorf("wrong exit code; got %d, expected %d", code, exp)
+		}
+	})
+
+	t.Run("grace period", func(t *testing.T) {
+		codes := mockExit(t)
+		var code int
+		root := newRoot(func(ctx context.Context) error {
+			interrupt(t)
+			<-ctx.Done()
+			select {
+			case code = <-codes:
+			case <-time.After(5 * time.Second):
+				t.Error("did not exit after the grace period")
+			}
+			return nil
+		})
+		root.GracePeriod = 10 * time.Millisecond
+		captureFile(t, &os.Stderr, func() { root.Run(context.Background(), []string{"sleep"}) })
+		if exp := 128 + int(syscall.SIGUSR1); code != exp {
+			t.Errorf("wrong exit code; got %d, expected %d", code, exp)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package alf
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// Stdin is the input stream for a command. It's Root.In if that's set,
+// otherwise it's os.Stdin. Use it with the context passed to a Command's Run
+// func, so that a whole tree's input can be replaced with one setting.
+func Stdin(ctx context.Context) io.Reader {
+	return frameFrom(ctx).root.stdin()
+}
+
+// Stdout is the output stream for a command. It's Root.Out if that's set,
+// otherwise it's os.Stdout. See Stdin.
+func Stdout(ctx context.Context) io.Writer {
+	return frameFrom(ctx).root.stdout()
+}
+
+// Stderr is the error stream for a command. It's Root.Err if that's set,
+// otherwise it's os.Stderr. See Stdin.
+func Stderr(ctx context.Context) io.Writer {
+	return frameFrom(ctx).root.stderr()
+}
+
+// The stream methods work with a nil Root, such as the one of a frame for a
+// Delegator that's used without a Root.
+
+func (r *Root) stdin() io.Reader {
+	if r == nil || r.In == nil {
+		return os.Stdin
+	}
+	return r.In
+}
+
+func (r *Root) stdout() io.Writer {
+	if r == nil || r.Out == nil {
+		return os.Stdout
+	}
+	return r.Out
+}
+
+func (r *Root) stderr() io.Writer {
+	if r == nil || r.Err == nil {
+		return os.Stderr
+	}
+	return r.Err
+}
@@ -0,0 +1,130 @@
+package alf_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+func TestRootStreams(t *testing.T) {
+	newRoot := func(stdin string, stdout, stderr *bytes.Buffer) *alf.Root {
+		return &alf.Root{
+			Delegator: &alf.Delegator{
+				Description: "root",
+				// The output of a flag set is replaced during Run, even one that's muted.
+				Flags: newMutedFlagSet("stub", flag.ContinueOnError),
+				Subs: map[string]alf.Directive{
+					"echo": &alf.Command{
+						Description: "copy stdin",
+						Setup: func(p flag.FlagSet) *flag.FlagSet {
+							p.Init("echo", flag.ContinueOnError)
+							return &p
+						},
+						Run: func(ctx context.Context) error {
+							in, err := io.ReadAll(alf.Stdin(ctx))
+							if err != nil {
+								return err
+							}
+							fmt.Fprintf(alf.Stdout(ctx), "out: %s", in)
+							fmt.Fprintf(alf.Stderr(ctx), "err: %s", in)
+							return nil
+						},
+					},
+					"fail": &alf.Command{
+						Description: "return an error",
+						Setup: func(p flag.FlagSet) *flag.FlagSet {
+							p.Init("fail", flag.ContinueOnError)
+							return &p
+						},
+						Run: func(ctx context.Context) error { return errors.New("oof") },
+					},
+				},
+			},
+			In:  strings.NewReader(stdin),
+			Out: stdout,
+			Err: stderr,
+		}
+	}
+
+	t.Run("command", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if err := newRoot("hello\n", &stdout, &stderr).Run(context.Background(), []string{"echo"}); err != nil {
+			t.Fatal(err)
+		}
+		if got := stdout.String(); got != "out: hello\n" {
+			t.Errorf("wrong stdout; got %q", got)
+		}
+		if got := stderr.String(); got != "err: hello\n" {
+			t.Errorf("wrong stderr; got %q", got)
+		}
+	})
+
+	t.Run("help", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		err := newRoot("", &stdout, &stderr).Run(context.Background(), []string{"echo", "-h"})
+		if !errors.Is(err, flag.ErrHelp) {
+			t.Errorf("expected flag.ErrHelp, got %v", err)
+		}
+		if !strings.Contains(stderr.String(), "Usage:\n\n\tstub echo [flags]") {
+			t.Errorf("help should go to Err, got %q", stderr.String())
+		}
+		if stdout.Len() > 0 {
+			t.Errorf("expected empty stdout, got %q", stdout.String())
+		}
+	})
+
+	t.Run("flag set output is put back", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		root := newRoot("", &stdout, &stderr)
+		orig := root.Flags.Output()
+		_ = root.Run(context.Background(), []string{"-h"})
+		if !strings.Contains(stderr.String(), "Usage:") {
+			t.Errorf("help should go to Err, got %q", stderr.String())
+		}
+		if root.Flags.Output() != orig {
+			t.Errorf("expected the original output after Run, got %T", root.Flags.Output())
+		}
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		var stdout, stderr bytes.Buffer
+		if err := newRoot("", &stdout, &stderr).Run(context.Background(), []string{"__complete", "ec"}); err != nil {
+			t.Fatal(err)
+		}
+		if got := stdout.String(); got != "echo\n" {
+			t.Errorf("wrong completions; got %q", got)
+		}
+	})
+
+	t.Run("main", func(t *testing.T) {
+		origArgs := os.Args
+		os.Args = []string{"stub", "fail"}
+		defer func() { os.Args = origArgs }()
+
+		code := -1
+		defer alf.SetOsExit(func(c int) { code = c })()
+		var stdout, stderr bytes.Buffer
+		newRoot("", &stdout, &stderr).Main(context.Background())
+		if code != 1 {
+			t.Errorf("wrong exit code; got %d", code)
+		}
+		if got := stderr.String(); got != "stub: oof\n" {
+			t.Errorf("wrong stderr; got %q", got)
+		}
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		ctx := context.Background()
+		if alf.Stdin(ctx) != os.Stdin || alf.Stdout(ctx) != os.Stdout || alf.Stderr(ctx) != os.Stderr {
+			t.Error("expected the streams from the os package without a Root")
+		}
+	})
+}
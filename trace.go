@@ -0,0 +1,40 @@
+package alf
+
+import "context"
+
+// Trace has optional hooks into Root.Run and Delegator.Perform, for testing
+// and debugging. Attach one to the context with WithTrace. Any of the hooks
+// may be nil. The path passed to a hook is the names of the subcommands
+// selected so far, like CommandPath. The hooks are called synchronously.
+type Trace struct {
+	// CommandSelected is called when a Delegator selects a subcommand, with
+	// the path to the subcommand.
+	CommandSelected func(path []string)
+	// UsageShown is called when the Usage func of the flag set at path is
+	// invoked, either by alf or by the flag package.
+	UsageShown func(path []string)
+}
+
+type traceKey struct{}
+
+// WithTrace attaches the Trace to the context. Pass the output to Root.Run.
+func WithTrace(ctx context.Context, trace *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+func traceFrom(ctx context.Context) *Trace {
+	trace, _ := ctx.Value(traceKey{}).(*Trace)
+	return trace
+}
+
+func (fr *frame) traceCommandSelected() {
+	if fr.trace != nil && fr.trace.CommandSelected != nil {
+		fr.trace.CommandSelected(append(make([]string, 0, len(fr.path)), fr.path...))
+	}
+}
+
+func (fr *frame) traceUsageShown() {
+	if fr.trace != nil && fr.trace.UsageShown != nil {
+		fr.trace.UsageShown(append(make([]string, 0, len(fr.path)), fr.path...))
+	}
+}
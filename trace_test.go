@@ -0,0 +1,52 @@
+package alf_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+func TestWithTrace(t *testing.T) {
+	tests := []struct {
+		args        []string
+		expSelected [][]string
+		expUsage    [][]string
+	}{
+		{
+			args:        []string{"delta", "echo"},
+			expSelected: [][]string{{"delta"}, {"delta", "echo"}},
+		},
+		{
+			args:        []string{"delta", "echo", "-h"},
+			expSelected: [][]string{{"delta"}, {"delta", "echo"}},
+			expUsage:    [][]string{{"delta", "echo"}},
+		},
+		{
+			args:        []string{"delta", "nope"},
+			expSelected: [][]string{{"delta"}},
+			expUsage:    [][]string{{"delta"}},
+		},
+		{
+			args:     []string{"-h"},
+			expUsage: [][]string{{}},
+		},
+	}
+	for _, test := range tests {
+		var selected, usage [][]string
+		ctx := alf.WithTrace(context.Background(), &alf.Trace{
+			CommandSelected: func(path []string) { selected = append(selected, path) },
+			UsageShown:      func(path []string) { usage = append(usage, path) },
+		})
+		var usageName string
+		root := newStubRoot("stub", &usageName, nil)
+		_ = root.Run(ctx, test.args)
+		if !reflect.DeepEqual(selected, test.expSelected) {
+			t.Errorf("%q: wrong selections; got %q, expected %q", test.args, selected, test.expSelected)
+		}
+		if !reflect.DeepEqual(usage, test.expUsage) {
+			t.Errorf("%q: wrong usage; got %q, expected %q", test.args, usage, test.expUsage)
+		}
+	}
+}
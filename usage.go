@@ -0,0 +1,203 @@
+package alf
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// UsageData is the input to a usage template. See Root.UsageTemplate.
+type UsageData struct {
+	// Path is the program name followed by the subcommand names, for example
+	// "bin bar cities".
+	Path string
+	// Args is a synopsis of a Command's positional arguments, such as
+	// "<country> [city...]". See Command.Args.
+	Args string
+	// ArgDescriptions describes each positional argument that has a
+	// Description.
+	ArgDescriptions []string
+	// Description is the Directive's summary.
+	Description string
+	// Subcommands describes the subcommands of a Delegator, in the format of
+	// (*Delegator).DescribeSubcommands. It's empty for a Command.
+	Subcommands []string
+	// Flags describes each flag in the format of (*flag.FlagSet).PrintDefaults.
+	// It's empty if there are no flags.
+	Flags string
+	// GlobalFlags describes the persistent flags from this level and its
+	// ancestors, in the same format as Flags. See Delegator.PersistentFlags.
+	GlobalFlags string
+	// Constraints describes the rules about the flags, see
+	// Command.Constraints and the required tag of Bind.
+	Constraints []string
+	// Env lists the environment variables that can set the flags, see
+	// Root.EnvPrefix and Bind. Each item is a variable name and the flag it
+	// sets.
+	Env []string
+}
+
+// DefaultUsageTemplate renders generated help messages unless a Root has its
+// own UsageTemplate.
+var DefaultUsageTemplate = template.Must(template.New("usage").Parse(`Usage:
+
+	{{.Path}} [flags]{{if .Subcommands}} subcommand [subflags]{{end}}{{with .Args}} {{.}}{{end}}
+{{- with .Description}}
+
+Description:
+
+	{{.}}
+{{- end}}
+{{- with .ArgDescriptions}}
+
+Arguments:
+{{range .}}
+	{{.}}
+{{- end}}
+{{- end}}
+{{- with .Subcommands}}
+
+Subcommands:
+
+	These will have their own set of flags. Put them after the subcommand.
+{{range .}}
+	{{.}}
+{{- end}}
+{{- end}}
+{{- with .Flags}}
+
+Flags:
+
+{{.}}
+{{- end}}
+{{- with .GlobalFlags}}
+
+Global flags:
+
+{{.}}
+{{- end}}
+{{- with .Constraints}}
+
+Constraints:
+{{range .}}
+	{{.}}
+{{- end}}
+{{- end}}
+{{- with .Env}}
+
+Environment variables:
+{{range .}}
+	{{.}}
+{{- end}}
+{{- end}}
+`))
+
+// generatedUsage is a flag set's Usage func made by alf.
+type generatedUsage struct {
+	fr    *frame
+	d     Directive
+	flags *flag.FlagSet
+}
+
+// setDefaultUsage gives flags a generated Usage func if it doesn't already have
+// one. A flag set created by flag.NewFlagSet starts off with a default Usage
+// func from the flag package, that counts as not having one. So does a Usage
+// func that alf generated for another flag set, which could happen when a
+// Command's Setup func reuses its parent flags.
+func setDefaultUsage(fr *frame, d Directive, flags *flag.FlagSet) {
+	if flags == nil || !usageIsUnset(flags.Usage) {
+		return
+	}
+	flags.Usage = (&generatedUsage{fr: fr, d: d, flags: flags}).print
+}
+
+var (
+	stdlibUsagePtr    = reflect.ValueOf(flag.NewFlagSet("", flag.ContinueOnError).Usage).Pointer()
+	generatedUsagePtr = reflect.ValueOf((&generatedUsage{}).print).Pointer()
+)
+
+func usageIsUnset(fn func()) bool {
+	if fn == nil {
+		return true
+	}
+	ptr := reflect.ValueOf(fn).Pointer()
+	return ptr == stdlibUsagePtr || ptr == generatedUsagePtr
+}
+
+func (u *generatedUsage) print() {
+	tmpl := DefaultUsageTemplate
+	if u.fr.root != nil && u.fr.root.UsageTemplate != nil {
+		tmpl = u.fr.root.UsageTemplate
+	}
+	out := u.flags.Output()
+	if err := tmpl.Execute(out, u.data()); err != nil {
+		fmt.Fprintf(out, "could not render usage for %s: %v\n", u.fr.commandPath(), err)
+	}
+}
+
+func (u *generatedUsage) data() UsageData {
+	out := UsageData{
+		Path:        u.fr.commandPath(),
+		Description: strings.TrimSpace(u.d.Summary()),
+	}
+	switch d := u.d.(type) {
+	case *Delegator:
+		out.Subcommands = d.DescribeSubcommands()
+		for _, c := range u.fr.requiredFlags(u.flags, false) {
+			out.Constraints = append(out.Constraints, c.String())
+		}
+	case *Command:
+		out.Args = argsSynopsis(d.Args)
+		for _, arg := range d.Args {
+			if arg.Description != "" {
+				out.ArgDescriptions = append(out.ArgDescriptions, fmt.Sprintf("%-20s\t%s", arg.Name, arg.Description))
+			}
+		}
+		for _, c := range d.constraints(u.fr, u.flags) {
+			out.Constraints = append(out.Constraints, c.String())
+		}
+	}
+	var flags, global bytes.Buffer
+	shorts := shortFlags(u.flags)
+	u.flags.VisitAll(func(f *flag.Flag) {
+		if isShortFlag(f) {
+			return
+		} else if u.fr.isPersistent(f) {
+			writeFlagDefault(&global, f, shorts[f.Name])
+		} else {
+			writeFlagDefault(&flags, f, shorts[f.Name])
+		}
+	})
+	out.Flags = strings.TrimRight(flags.String(), "\n")
+	out.GlobalFlags = strings.TrimRight(global.String(), "\n")
+	for _, binding := range u.fr.envBindings(u.flags) {
+		out.Env = append(out.Env, fmt.Sprintf("%-24s\t-%s", binding.name, binding.flag.Name))
+	}
+	return out
+}
+
+// writeFlagDefault outputs a flag like (*flag.FlagSet).PrintDefaults. If the
+// flag has a short form, it's shown first.
+func writeFlagDefault(w *bytes.Buffer, f *flag.Flag, short string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "  %s", flagNames(f, short))
+	typeName, usage := unquoteUsage(f)
+	if typeName != "" {
+		b.WriteString(" " + typeName)
+	}
+	// Boolean flags of one ASCII letter are so common we treat them
+	// specially, putting their usage on the same line.
+	if b.Len() <= 4 {
+		b.WriteString("\t")
+	} else {
+		b.WriteString("\n    \t")
+	}
+	b.WriteString(strings.ReplaceAll(usage, "\n", "\n    \t"))
+	if def, ok := flagDefault(f); ok {
+		fmt.Fprintf(&b, " (default %s)", def)
+	}
+	fmt.Fprintln(w, b.String())
+}
@@ -0,0 +1,135 @@
+package alf_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+func TestGeneratedUsage(t *testing.T) {
+	newRoot := func(out *bytes.Buffer) *alf.Root {
+		rootFlags := flag.NewFlagSet("prog", flag.ContinueOnError)
+		rootFlags.SetOutput(out)
+		rootFlags.String("foo", "frank", "root flag example")
+
+		sub := alf.Delegator{
+			Description: "subcmd with more subs",
+			Flags:       flag.NewFlagSet("sub", flag.ContinueOnError),
+		}
+		sub.Flags.SetOutput(out)
+		sub.Flags.Int("bar", 2, "bbb")
+		sub.Subs = map[string]alf.Directive{
+			"reuse": &alf.Command{
+				Description: "reuses parent flags",
+				Setup: func(p flag.FlagSet) *flag.FlagSet {
+					p.Init("reuse", flag.ContinueOnError)
+					p.Bool("quebec", true, "qqq")
+					return &p
+				},
+				Run: func(ctx context.Context) error { return alf.ErrShowUsage },
+			},
+			"custom": &alf.Command{
+				Description: "has its own usage",
+				Setup: func(p flag.FlagSet) *flag.FlagSet {
+					f := flag.NewFlagSet("custom", flag.ContinueOnError)
+					f.SetOutput(out)
+					f.Usage = func() { out.WriteString("custom usage") }
+					return f
+				},
+				Run: func(ctx context.Context) error { return nil },
+			},
+		}
+
+		return &alf.Root{
+			Delegator: &alf.Delegator{
+				Description: "the root",
+				Flags:       rootFlags,
+				Subs:        map[string]alf.Directive{"sub": &sub},
+			},
+		}
+	}
+
+	tests := []struct {
+		args             []string
+		expMentions      []string
+		expNotMentioned  []string
+		expErrIsHelpFlag bool
+	}{
+		{
+			args: []string{"-h"},
+			expMentions: []string{
+				"Usage:\n\n\tprog [flags] subcommand [subflags]\n",
+				"\tthe root\n",
+				"\tsub                 \tsubcmd with more subs",
+				"  -foo string\n    \troot flag example (default \"frank\")\n",
+			},
+			expErrIsHelpFlag: true,
+		},
+		{
+			args: []string{"sub", "-h"},
+			expMentions: []string{
+				"\tprog sub [flags] subcommand [subflags]\n",
+				"\tsubcmd with more subs\n",
+				"\treuse               \treuses parent flags",
+				"  -bar int\n    \tbbb (default 2)\n",
+			},
+			expNotMentioned:  []string{"-foo"},
+			expErrIsHelpFlag: true,
+		},
+		{
+			args: []string{"sub", "reuse"},
+			expMentions: []string{
+				"\tprog sub reuse [flags]\n",
+				"\treuses parent flags\n",
+				"  -bar int\n",
+				"  -quebec\n    \tqqq (default true)\n",
+			},
+			expNotMentioned: []string{"Subcommands:", "subcmd with more subs"},
+		},
+		{
+			args:             []string{"sub", "custom", "-h"},
+			expMentions:      []string{"custom usage"},
+			expNotMentioned:  []string{"Usage:"},
+			expErrIsHelpFlag: true,
+		},
+	}
+
+	for _, test := range tests {
+		var out bytes.Buffer
+		err := newRoot(&out).Run(context.Background(), test.args)
+		if err == nil {
+			t.Errorf("args %q; expected error", test.args)
+		}
+		if errors.Is(err, flag.ErrHelp) != test.expErrIsHelpFlag {
+			t.Errorf("args %q; wrong error %v", test.args, err)
+		}
+		for _, mention := range test.expMentions {
+			if !strings.Contains(out.String(), mention) {
+				t.Errorf("args %q; output does not contain %q\n%s", test.args, mention, out.String())
+			}
+		}
+		for _, mention := range test.expNotMentioned {
+			if strings.Contains(out.String(), mention) {
+				t.Errorf("args %q; output should not contain %q\n%s", test.args, mention, out.String())
+			}
+		}
+	}
+
+	t.Run("UsageTemplate", func(t *testing.T) {
+		var out bytes.Buffer
+		root := newRoot(&out)
+		root.UsageTemplate = template.Must(template.New("").Parse("{{.Path}}: {{.Description}}; {{len .Subcommands}} subcommands"))
+		if err := root.Run(context.Background(), []string{"sub"}); err == nil {
+			t.Error("expected error")
+		}
+		if exp := "prog sub: subcmd with more subs; 2 subcommands"; out.String() != exp {
+			t.Errorf("got %q, expected %q", out.String(), exp)
+		}
+	})
+}
@@ -0,0 +1,417 @@
+package alf
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file has flag.Value implementations for types that the flag package
+// doesn't cover. Define them on any flag set, like the ones for a Delegator or
+// from a Command's Setup func. Each XxxVar func works like its counterpart in
+// the flag package, such as (*flag.FlagSet).StringVar.
+
+// typeNamer is implemented by the flag values in this file, to name their type
+// in help messages.
+type typeNamer interface{ typeName() string }
+
+// unquoteUsage is like flag.UnquoteUsage, and it also knows the type names of
+// the flag values in this file and looks through the ones that Bind wraps.
+func unquoteUsage(f *flag.Flag) (name, usage string) {
+	if b, ok := f.Value.(*boundValue); ok {
+		inner := *f
+		inner.Value = b.Value
+		return unquoteUsage(&inner)
+	}
+	name, usage = flag.UnquoteUsage(f)
+	if t, ok := f.Value.(typeNamer); ok && name == "value" {
+		name = t.typeName()
+	}
+	return
+}
+
+// StringsVar defines a flag that can be repeated, collecting each value. For
+// example, "-tag a -tag b" makes ["a", "b"]. Specifying the flag replaces the
+// default value rather than adding to it.
+func StringsVar(fs *flag.FlagSet, p *[]string, name string, value []string, usage string) {
+	*p = append([]string(nil), value...)
+	fs.Var(&stringsValue{p: p}, name, usage)
+}
+
+type stringsValue struct {
+	p   *[]string
+	set bool
+}
+
+func (v *stringsValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return strings.Join(*v.p, ",")
+}
+
+func (v *stringsValue) Set(val string) error {
+	if !v.set {
+		*v.p, v.set = nil, true
+	}
+	*v.p = append(*v.p, val)
+	return nil
+}
+
+func (v *stringsValue) Get() any         { return *v.p }
+func (v *stringsValue) typeName() string { return "strings" }
+
+// EnumVar defines a flag that only accepts one of the choices. The choices are
+// appended to the usage, so they're listed in help messages, and they're
+// suggested during shell completion.
+func EnumVar(fs *flag.FlagSet, p *string, name string, value string, choices []string, usage string) {
+	*p = value
+	usage = fmt.Sprintf("%s (one of: %s)", usage, strings.Join(choices, ", "))
+	fs.Var(&enumValue{p: p, choices: choices}, name, usage)
+}
+
+type enumValue struct {
+	p       *string
+	choices []string
+}
+
+func (v *enumValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	return *v.p
+}
+
+func (v *enumValue) Set(val string) error {
+	for _, choice := range v.choices {
+		if val == choice {
+			*v.p = val
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(v.choices, ", "))
+}
+
+func (v *enumValue) Get() any         { return *v.p }
+func (v *enumValue) typeName() string { return "string" }
+
+// Complete suggests the choices starting with toComplete.
+func (v *enumValue) Complete(ctx context.Context, args []string, toComplete string) (out []string) {
+	for _, choice := range v.choices {
+		if strings.HasPrefix(choice, toComplete) {
+			out = append(out, choice)
+		}
+	}
+	return
+}
+
+// MapVar defines a flag that collects key=value pairs. It can be repeated, as
+// in "-label env=prod -label team=core", and each value can also have several
+// comma-separated pairs. Specifying the flag replaces the default value rather
+// than adding to it.
+func MapVar(fs *flag.FlagSet, p *map[string]string, name string, value map[string]string, usage string) {
+	*p = make(map[string]string, len(value))
+	for key, val := range value {
+		(*p)[key] = val
+	}
+	fs.Var(&mapValue{p: p}, name, usage)
+}
+
+type mapValue struct {
+	p   *map[string]string
+	set bool
+}
+
+func (v *mapValue) String() string {
+	if v.p == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(*v.p))
+	for key, val := range *v.p {
+		pairs = append(pairs, key+"="+val)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (v *mapValue) Set(val string) error {
+	if !v.set {
+		*v.p, v.set = make(map[string]string), true
+	}
+	for _, pair := range strings.Split(val, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("expected key=value, got %q", pair)
+		}
+		(*v.p)[key] = val
+	}
+	return nil
+}
+
+func (v *mapValue) Get() any         { return *v.p }
+func (v *mapValue) typeName() string { return "key=value" }
+
+// ByteSize is a number of bytes. It's parsed from a number with an optional
+// unit, such as "512", "10MiB" or "1.5GB". Decimal units (kB, MB, GB, TB, PB)
+// are powers of 1000, binary units (KiB, MiB, GiB, TiB, PiB) are powers of
+// 1024. Units are not case-sensitive.
+type ByteSize uint64
+
+// Binary and decimal units of ByteSize.
+const (
+	Byte ByteSize = 1
+
+	KiB = 1024 * Byte
+	MiB = 1024 * KiB
+	GiB = 1024 * MiB
+	TiB = 1024 * GiB
+	PiB = 1024 * TiB
+
+	KB = 1000 * Byte
+	MB = 1000 * KB
+	GB = 1000 * MB
+	TB = 1000 * GB
+	PB = 1000 * TB
+)
+
+var byteSizeUnits = []struct {
+	name string
+	size ByteSize
+}{
+	// Ordered by size, largest first, binary before decimal. String uses the
+	// first one that fits evenly.
+	{"PiB", PiB}, {"PB", PB}, {"TiB", TiB}, {"TB", TB}, {"GiB", GiB}, {"GB", GB},
+	{"MiB", MiB}, {"MB", MB}, {"KiB", KiB}, {"kB", KB}, {"B", Byte},
+}
+
+// ParseByteSize parses a string like "10MiB". See ByteSize.
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	num := s
+	if i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' }); i >= 0 {
+		num = s[:i]
+	}
+	unit := strings.TrimSpace(s[len(num):])
+	if num == "" {
+		return 0, fmt.Errorf("invalid byte size %q, expected a number like 512, 10MiB or 1.5GB", s)
+	}
+
+	size := Byte
+	if unit != "" {
+		var ok bool
+		for _, u := range byteSizeUnits {
+			if strings.EqualFold(unit, u.name) {
+				size, ok = u.size, true
+				break
+			}
+		}
+		if !ok {
+			return 0, fmt.Errorf("invalid byte size unit %q, expected one of B, kB, MB, GB, TB, PB, KiB, MiB, GiB, TiB, PiB", unit)
+		}
+	}
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid byte size %q, expected a number like 512, 10MiB or 1.5GB", s)
+	}
+	bytes := f * float64(size)
+	if bytes >= math.MaxUint64 {
+		return 0, fmt.Errorf("byte size %q is too large", s)
+	}
+	return ByteSize(bytes), nil
+}
+
+// String formats the size with the largest unit that divides it evenly.
+func (b ByteSize) String() string {
+	if b == 0 {
+		return "0"
+	}
+	for _, u := range byteSizeUnits {
+		if b%u.size == 0 {
+			return strconv.FormatUint(uint64(b/u.size), 10) + u.name
+		}
+	}
+	return strconv.FormatUint(uint64(b), 10) + "B"
+}
+
+// ByteSizeVar defines a ByteSize flag.
+func ByteSizeVar(fs *flag.FlagSet, p *ByteSize, name string, value ByteSize, usage string) {
+	*p = value
+	fs.Var((*byteSizeValue)(p), name, usage)
+}
+
+type byteSizeValue ByteSize
+
+func (v *byteSizeValue) String() string {
+	if v == nil {
+		return "0"
+	}
+	return ByteSize(*v).String()
+}
+
+func (v *byteSizeValue) Set(val string) error {
+	size, err := ParseByteSize(val)
+	if err != nil {
+		return err
+	}
+	*v = byteSizeValue(size)
+	return nil
+}
+
+func (v *byteSizeValue) Get() any         { return ByteSize(*v) }
+func (v *byteSizeValue) typeName() string { return "size" }
+
+// TimeVar defines a flag for a point in time. It accepts an RFC 3339 timestamp,
+// such as "2006-01-02T15:04:05Z", or a date, such as "2006-01-02", which is
+// midnight UTC.
+func TimeVar(fs *flag.FlagSet, p *time.Time, name string, value time.Time, usage string) {
+	*p = value
+	fs.Var((*timeValue)(p), name, usage)
+}
+
+type timeValue time.Time
+
+func (v *timeValue) String() string {
+	if v == nil || time.Time(*v).IsZero() {
+		return ""
+	}
+	return time.Time(*v).Format(time.RFC3339)
+}
+
+func (v *timeValue) Set(val string) error {
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, val); err == nil {
+			*v = timeValue(t)
+			return nil
+		}
+	}
+	return errors.New("expected an RFC 3339 time like 2006-01-02T15:04:05Z or a date like 2006-01-02")
+}
+
+func (v *timeValue) Get() any         { return time.Time(*v) }
+func (v *timeValue) typeName() string { return "time" }
+
+// URLVar defines a flag for an absolute URL, one with a scheme such as
+// "https://". The value may be nil.
+func URLVar(fs *flag.FlagSet, p **url.URL, name string, value *url.URL, usage string) {
+	*p = value
+	fs.Var(&urlValue{p: p}, name, usage)
+}
+
+type urlValue struct{ p **url.URL }
+
+func (v *urlValue) String() string {
+	if v.p == nil || *v.p == nil {
+		return ""
+	}
+	return (*v.p).String()
+}
+
+func (v *urlValue) Set(val string) error {
+	u, err := url.Parse(val)
+	if uerr, ok := err.(*url.Error); ok {
+		return uerr.Err
+	} else if err != nil {
+		return err
+	}
+	if u.Scheme == "" {
+		return errors.New("expected an absolute URL with a scheme, like https://example.com")
+	}
+	*v.p = u
+	return nil
+}
+
+func (v *urlValue) Get() any         { return *v.p }
+func (v *urlValue) typeName() string { return "url" }
+
+// IPVar defines a flag for an IPv4 or IPv6 address.
+func IPVar(fs *flag.FlagSet, p *net.IP, name string, value net.IP, usage string) {
+	*p = value
+	fs.Var((*ipValue)(p), name, usage)
+}
+
+type ipValue net.IP
+
+func (v *ipValue) String() string {
+	if v == nil || *v == nil {
+		return ""
+	}
+	return net.IP(*v).String()
+}
+
+func (v *ipValue) Set(val string) error {
+	ip := net.ParseIP(val)
+	if ip == nil {
+		return errors.New("expected an IP address like 192.0.2.1 or 2001:db8::1")
+	}
+	*v = ipValue(ip)
+	return nil
+}
+
+func (v *ipValue) Get() any         { return net.IP(*v) }
+func (v *ipValue) typeName() string { return "ip" }
+
+// IPNetVar defines a flag for an IP network in CIDR notation, such as
+// "192.0.2.0/24".
+func IPNetVar(fs *flag.FlagSet, p *net.IPNet, name string, value net.IPNet, usage string) {
+	*p = value
+	fs.Var((*ipNetValue)(p), name, usage)
+}
+
+type ipNetValue net.IPNet
+
+func (v *ipNetValue) String() string {
+	if v == nil || v.IP == nil {
+		return ""
+	}
+	return (*net.IPNet)(v).String()
+}
+
+func (v *ipNetValue) Set(val string) error {
+	_, ipNet, err := net.ParseCIDR(val)
+	if err != nil {
+		return errors.New("expected CIDR notation like 192.0.2.0/24 or 2001:db8::/32")
+	}
+	*v = ipNetValue(*ipNet)
+	return nil
+}
+
+func (v *ipNetValue) Get() any         { return net.IPNet(*v) }
+func (v *ipNetValue) typeName() string { return "cidr" }
+
+// RegexpVar defines a flag for a regular expression, in the syntax of the
+// regexp package. The value may be nil.
+func RegexpVar(fs *flag.FlagSet, p **regexp.Regexp, name string, value *regexp.Regexp, usage string) {
+	*p = value
+	fs.Var(&regexpValue{p: p}, name, usage)
+}
+
+type regexpValue struct{ p **regexp.Regexp }
+
+func (v *regexpValue) String() string {
+	if v.p == nil || *v.p == nil {
+		return ""
+	}
+	return (*v.p).String()
+}
+
+func (v *regexpValue) Set(val string) error {
+	re, err := regexp.Compile(val)
+	if err != nil {
+		return err
+	}
+	*v.p = re
+	return nil
+}
+
+func (v *regexpValue) Get() any         { return *v.p }
+func (v *regexpValue) typeName() string { return "regexp" }
@@ -0,0 +1,227 @@
+package alf_test
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+func TestValues(t *testing.T) {
+	type target struct {
+		strs   []string
+		enum   string
+		labels map[string]string
+		size   alf.ByteSize
+		when   time.Time
+		link   *url.URL
+		ip     net.IP
+		ipNet  net.IPNet
+		re     *regexp.Regexp
+	}
+	newFlags := func(dst *target) *flag.FlagSet {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		alf.StringsVar(flags, &dst.strs, "tag", []string{"default"}, "tags")
+		alf.EnumVar(flags, &dst.enum, "env", "dev", []string{"dev", "prod"}, "environment")
+		alf.MapVar(flags, &dst.labels, "label", map[string]string{"team": "core"}, "labels")
+		alf.ByteSizeVar(flags, &dst.size, "size", 512*alf.KiB, "size")
+		alf.TimeVar(flags, &dst.when, "since", time.Time{}, "since")
+		alf.URLVar(flags, &dst.link, "url", nil, "url")
+		alf.IPVar(flags, &dst.ip, "ip", nil, "ip")
+		alf.IPNetVar(flags, &dst.ipNet, "cidr", net.IPNet{}, "cidr")
+		alf.RegexpVar(flags, &dst.re, "match", nil, "match")
+		return flags
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		var got target
+		if err := newFlags(&got).Parse(nil); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got.strs, []string{"default"}) {
+			t.Errorf("wrong strs; got %q", got.strs)
+		}
+		if got.enum != "dev" {
+			t.Errorf("wrong enum; got %q", got.enum)
+		}
+		if !reflect.DeepEqual(got.labels, map[string]string{"team": "core"}) {
+			t.Errorf("wrong labels; got %v", got.labels)
+		}
+		if got.size != 512*alf.KiB {
+			t.Errorf("wrong size; got %d", got.size)
+		}
+		if got.link != nil || got.ip != nil || got.re != nil || !got.when.IsZero() {
+			t.Errorf("expected zero values, got %+v", got)
+		}
+	})
+
+	t.Run("set", func(t *testing.T) {
+		var got target
+		err := newFlags(&got).Parse([]string{
+			"-tag", "a", "-tag", "b",
+			"-env", "prod",
+			"-label", "env=prod,tier=web", "-label", "zone=",
+			"-size", "1.5GB",
+			"-since", "2006-01-02",
+			"-url", "https://example.com/x",
+			"-ip", "2001:db8::1",
+			"-cidr", "192.0.2.0/24",
+			"-match", "^a+$",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got.strs, []string{"a", "b"}) {
+			t.Errorf("wrong strs; got %q", got.strs)
+		}
+		if got.enum != "prod" {
+			t.Errorf("wrong enum; got %q", got.enum)
+		}
+		if exp := map[string]string{"env": "prod", "tier": "web", "zone": ""}; !reflect.DeepEqual(got.labels, exp) {
+			t.Errorf("wrong labels; got %v, expected %v", got.labels, exp)
+		}
+		if got.size != 1500*alf.MB {
+			t.Errorf("wrong size; got %d", got.size)
+		}
+		if exp := time.Date(2006, 1, 2, 0, 0, 0, 0, time.UTC); !got.when.Equal(exp) {
+			t.Errorf("wrong time; got %v, expected %v", got.when, exp)
+		}
+		if got.link == nil || got.link.Host != "example.com" {
+			t.Errorf("wrong url; got %v", got.link)
+		}
+		if !got.ip.Equal(net.ParseIP("2001:db8::1")) {
+			t.Errorf("wrong ip; got %v", got.ip)
+		}
+		if got.ipNet.String() != "192.0.2.0/24" {
+			t.Errorf("wrong cidr; got %v", got.ipNet.String())
+		}
+		if got.re == nil || !got.re.MatchString("aaa") {
+			t.Errorf("wrong regexp; got %v", got.re)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tests := []struct {
+			args   []string
+			expErr string
+		}{
+			{args: []string{"-env", "qa"}, expErr: "must be one of dev, prod"},
+			{args: []string{"-label", "oops"}, expErr: `expected key=value, got "oops"`},
+			{args: []string{"-label", "=x"}, expErr: `expected key=value, got "=x"`},
+			{args: []string{"-size", "10XB"}, expErr: `invalid byte size unit "XB"`},
+			{args: []string{"-size", "MiB"}, expErr: `invalid byte size "MiB"`},
+			{args: []string{"-size", "1.2.3"}, expErr: `invalid byte size "1.2.3"`},
+			{args: []string{"-since", "yesterday"}, expErr: "expected an RFC 3339 time"},
+			{args: []string{"-url", "example.com"}, expErr: "expected an absolute URL"},
+			{args: []string{"-url", "http://[::1"}, expErr: "missing ']' in host"},
+			{args: []string{"-ip", "300.0.0.1"}, expErr: "expected an IP address"},
+			{args: []string{"-cidr", "192.0.2.0"}, expErr: "expected CIDR notation"},
+			{args: []string{"-match", "a("}, expErr: "missing closing )"},
+		}
+		for _, test := range tests {
+			var got target
+			err := newFlags(&got).Parse(test.args)
+			if err == nil {
+				t.Errorf("%q: expected error, got none", test.args)
+			} else if !strings.Contains(err.Error(), test.expErr) {
+				t.Errorf("%q: error %q should contain %q", test.args, err, test.expErr)
+			}
+		}
+	})
+}
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		in     string
+		exp    alf.ByteSize
+		expStr string
+	}{
+		{in: "0", exp: 0, expStr: "0"},
+		{in: "512", exp: 512, expStr: "512B"},
+		{in: "512 b", exp: 512, expStr: "512B"},
+		{in: "1kb", exp: alf.KB, expStr: "1kB"},
+		{in: "2KiB", exp: 2 * alf.KiB, expStr: "2KiB"},
+		{in: "1.5MiB", exp: 1536 * alf.KiB, expStr: "1536KiB"},
+		{in: "10GB", exp: 10 * alf.GB, expStr: "10GB"},
+		{in: "3TiB", exp: 3 * alf.TiB, expStr: "3TiB"},
+		{in: "1PB", exp: alf.PB, expStr: "1PB"},
+		{in: "1000", exp: alf.KB, expStr: "1kB"},
+	}
+	for _, test := range tests {
+		got, err := alf.ParseByteSize(test.in)
+		if err != nil {
+			t.Errorf("%q: %v", test.in, err)
+			continue
+		}
+		if got != test.exp {
+			t.Errorf("%q: got %d, expected %d", test.in, got, test.exp)
+		}
+		if got.String() != test.expStr {
+			t.Errorf("%q: got %q, expected %q", test.in, got.String(), test.expStr)
+		}
+	}
+
+	for _, in := range []string{"", "-1", "1e3", "99999PiB"} {
+		if _, err := alf.ParseByteSize(in); err == nil {
+			t.Errorf("%q: expected error, got none", in)
+		}
+	}
+}
+
+func TestValuesUsage(t *testing.T) {
+	var (
+		env  string
+		tags []string
+		size alf.ByteSize
+		out  bytes.Buffer
+	)
+	root := &alf.Root{
+		Delegator: &alf.Delegator{Description: "root"},
+	}
+	root.Subs = map[string]alf.Directive{
+		"deploy": &alf.Command{
+			Description: "deploy it",
+			Setup: func(p flag.FlagSet) *flag.FlagSet {
+				f := flag.NewFlagSet("deploy", flag.ContinueOnError)
+				f.SetOutput(&out)
+				alf.EnumVar(f, &env, "env", "dev", []string{"dev", "staging", "prod"}, "where to deploy")
+				alf.StringsVar(f, &tags, "tag", nil, "image tags")
+				alf.ByteSizeVar(f, &size, "max-size", 10*alf.MiB, "largest upload")
+				return f
+			},
+			Run: func(ctx context.Context) error { return nil },
+		},
+	}
+	root.Flags = newMutedFlagSet("root", flag.ContinueOnError)
+
+	_ = root.Run(context.Background(), []string{"deploy", "-h"})
+	got := out.String()
+	for _, exp := range []string{
+		"-env string\n    \twhere to deploy (one of: dev, staging, prod) (default \"dev\")",
+		"-tag strings\n    \timage tags\n",
+		"-max-size size\n    \tlargest upload (default 10MiB)",
+	} {
+		if !strings.Contains(got, exp) {
+			t.Errorf("usage should contain %q\n%s", exp, got)
+		}
+	}
+
+	completions := captureStdout(t, func() {
+		if err := root.Run(context.Background(), []string{"__complete", "deploy", "-env", "st"}); err != nil {
+			t.Error(err)
+		}
+	})
+	if strings.TrimSpace(completions) != "staging" {
+		t.Errorf("wrong completions; got %q", completions)
+	}
+}
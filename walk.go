@@ -0,0 +1,102 @@
+package alf
+
+import (
+	"errors"
+	"flag"
+	"sort"
+)
+
+// A WalkFunc is called by Walk for each Directive in the command tree. The path
+// is the sequence of subcommand names leading to d, it's empty for the root.
+// The flags are a Delegator's Flags or what a Command's Setup func returns, it
+// may be nil for other kinds of Directive.
+//
+// If the function returns SkipSubtree while visiting a Delegator, then Walk
+// skips the Delegator's subcommands. Any other error stops the walk.
+type WalkFunc func(path []string, d Directive, flags *flag.FlagSet) error
+
+// SkipSubtree is used as a return value from a WalkFunc to indicate that the
+// subcommands of the visited Delegator should be skipped. It's not returned as
+// an error by Walk.
+var SkipSubtree = errors.New("skip this subtree")
+
+// Walk visits root and its descendants depth-first, in sorted name order. For a
+// Delegator, fn is called before its subcommands are visited. Each Command's
+// flags are resolved by calling its Setup func with a copy of its parent's
+// flags, so that the parent flags are not modified. The flags passed to fn
+// include any persistent flags from the Delegator and its ancestors. Nothing is
+// parsed and no Command is run, see Command.Setup.
+func Walk(root Directive, fn WalkFunc) error {
+	return walk(root, nil, nil, nil, fn)
+}
+
+func walk(d Directive, path []string, parentFlags *flag.FlagSet, persistent []*flag.Flag, fn WalkFunc) error {
+	switch node := d.(type) {
+	case *Root:
+		return walk(node.Delegator, path, parentFlags, persistent, fn)
+	case *Delegator:
+		persistent = appendPersistent(persistent, node)
+		flags := node.Flags
+		if flags != nil {
+			flags = withPersistent(flags, persistent)
+		}
+		if err := fn(path, node, flags); err == SkipSubtree {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if flags == nil {
+			flags = withPersistent(flag.NewFlagSet("", flag.ContinueOnError), persistent)
+		}
+		for _, name := range sortedKeys(node.Subs) {
+			subpath := append(path[:len(path):len(path)], name)
+			if err := walk(node.Subs[name], subpath, flags, persistent, fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *Command:
+		return skipped(fn(path, node, withPersistent(node.inspectFlags(parentFlags), persistent)))
+	default:
+		return skipped(fn(path, d, nil))
+	}
+}
+
+// skipped ignores SkipSubtree for a Directive without a subtree.
+func skipped(err error) error {
+	if err == SkipSubtree {
+		return nil
+	}
+	return err
+}
+
+// cloneFlagSet makes a new flag set with the same name, settings and flags as
+// f. The flag values are shared, but adding flags to the clone does not affect
+// the original.
+func cloneFlagSet(f *flag.FlagSet) *flag.FlagSet {
+	out := flag.NewFlagSet(f.Name(), f.ErrorHandling())
+	out.SetOutput(f.Output())
+	if f.Usage != nil {
+		out.Usage = f.Usage
+	}
+	f.VisitAll(func(fl *flag.Flag) {
+		out.Var(fl.Value, fl.Name, fl.Usage)
+		out.Lookup(fl.Name).DefValue = fl.DefValue
+	})
+	return out
+}
+
+// isBoolFlag reports whether the flag can be specified without a value.
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func sortedKeys(subs map[string]Directive) []string {
+	names := make([]string, 0, len(subs))
+	for name := range subs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,113 @@
+package alf_test
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/rafaelespinoza/alf"
+)
+
+func TestWalk(t *testing.T) {
+	type visit struct {
+		path  string
+		kind  string
+		flags string
+	}
+
+	// collect records each visit. The subtree at the skip path is skipped.
+	collect := func(t *testing.T, root alf.Directive, skip string) []visit {
+		t.Helper()
+		var visits []visit
+		err := alf.Walk(root, func(path []string, d alf.Directive, flags *flag.FlagSet) error {
+			v := visit{path: strings.Join(path, " ")}
+			switch d.(type) {
+			case *alf.Delegator:
+				v.kind = "delegator"
+			case *alf.Command:
+				v.kind = "command"
+			}
+			var names []string
+			flags.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+			v.flags = strings.Join(names, ",")
+			visits = append(visits, v)
+			if v.path == skip {
+				return alf.SkipSubtree
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return visits
+	}
+
+	t.Run("visits every node in order", func(t *testing.T) {
+		root := newStubRoot("stub", nil, nil)
+		got := collect(t, &root, "-")
+		expected := []visit{
+			{path: "", kind: "delegator", flags: "foo"},
+			{path: "alpha", kind: "command"},
+			{path: "bravo", kind: "command"},
+			{path: "charlie", kind: "command"},
+			{path: "delta", kind: "delegator", flags: "bar"},
+			{path: "delta echo", kind: "command", flags: "bar,quebec"},
+			{path: "delta foxtrot", kind: "command", flags: "qux"},
+			{path: "delta golf", kind: "command", flags: "bar"},
+			{path: "delta hotel", kind: "command"},
+			{path: "delta india", kind: "delegator"},
+			{path: "delta india bar", kind: "command"},
+			{path: "delta india foo", kind: "command"},
+		}
+		if len(got) != len(expected) {
+			t.Fatalf("wrong number of visits; got %d, expected %d\n%v", len(got), len(expected), got)
+		}
+		for i, v := range got {
+			if v != expected[i] {
+				t.Errorf("visit %d; got %+v, expected %+v", i, v, expected[i])
+			}
+		}
+	})
+
+	t.Run("does not modify parent flags", func(t *testing.T) {
+		root := newStubRoot("stub", nil, nil)
+		// Walk more than once, the "delta echo" Command adds a flag to a copy
+		// of its parent's flags. That would panic if it were the same flag set.
+		collect(t, root.Delegator, "-")
+		collect(t, root.Delegator, "-")
+		if f := root.Subs["delta"].(*alf.Delegator).Flags.Lookup("quebec"); f != nil {
+			t.Errorf("parent flags should not have child flag %q", f.Name)
+		}
+	})
+
+	t.Run("SkipSubtree", func(t *testing.T) {
+		root := newStubRoot("stub", nil, nil)
+		got := collect(t, &root, "delta")
+		var paths []string
+		for _, v := range got {
+			paths = append(paths, v.path)
+		}
+		if exp := ",alpha,bravo,charlie,delta"; strings.Join(paths, ",") != exp {
+			t.Errorf("got %q, expected %q", paths, exp)
+		}
+	})
+
+	t.Run("stops on error", func(t *testing.T) {
+		root := newStubRoot("stub", nil, nil)
+		var visits int
+		err := alf.Walk(&root, func(path []string, d alf.Directive, flags *flag.FlagSet) error {
+			visits++
+			if len(path) > 0 && path[0] == "bravo" {
+				return errStub
+			}
+			return nil
+		})
+		if !errors.Is(err, errStub) {
+			t.Errorf("expected %v, got %v", errStub, err)
+		}
+		if visits != 3 {
+			t.Errorf("wrong number of visits; got %d, expected %d", visits, 3)
+		}
+	})
+}